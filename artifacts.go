@@ -0,0 +1,104 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// normativeArtifactTypes are the artifact types of the TOSCA Simple Profile.
+// They are not part of the embedded normative types and are accepted without definition.
+var normativeArtifactTypes = map[string]ArtifactType{
+	"tosca.artifacts.Root":                      {},
+	"tosca.artifacts.File":                      {DerivedFrom: "tosca.artifacts.Root"},
+	"tosca.artifacts.Deployment":                {DerivedFrom: "tosca.artifacts.Root"},
+	"tosca.artifacts.Deployment.Image":          {DerivedFrom: "tosca.artifacts.Deployment"},
+	"tosca.artifacts.Deployment.Image.VM":       {DerivedFrom: "tosca.artifacts.Deployment.Image"},
+	"tosca.artifacts.Implementation":            {DerivedFrom: "tosca.artifacts.Root"},
+	"tosca.artifacts.Implementation.Bash":       {DerivedFrom: "tosca.artifacts.Implementation", MimeType: "application/x-sh", FileExt: []string{"sh"}},
+	"tosca.artifacts.Implementation.Python":     {DerivedFrom: "tosca.artifacts.Implementation", MimeType: "application/x-python", FileExt: []string{"py"}},
+	"tosca.artifacts.Deployment.Image.VM.ISO":   {DerivedFrom: "tosca.artifacts.Deployment.Image.VM", MimeType: "application/octet-stream", FileExt: []string{"iso"}},
+	"tosca.artifacts.Deployment.Image.VM.QCOW2": {DerivedFrom: "tosca.artifacts.Deployment.Image.VM", MimeType: "application/octet-stream", FileExt: []string{"qcow2"}},
+}
+
+// ArtifactType returns the artifact type named name with the mime type, the file extensions
+// and the properties inherited from its ancestors
+func (s *ServiceTemplateDefinition) ArtifactType(name string) (ArtifactType, error) {
+	var chain []ArtifactType
+	visited := make(map[string]bool)
+	for n := name; n != ""; {
+		if visited[n] {
+			return ArtifactType{}, fmt.Errorf("Artifact type %v: cyclic derivation through %v", name, n)
+		}
+		visited[n] = true
+		at, ok := s.ArtifactTypes[n]
+		if !ok {
+			at, ok = normativeArtifactTypes[n]
+		}
+		if !ok {
+			return ArtifactType{}, fmt.Errorf("Artifact type %v not found", n)
+		}
+		chain = append(chain, at)
+		n = at.DerivedFrom
+	}
+	flat := ArtifactType{
+		DerivedFrom: chain[0].DerivedFrom,
+		Version:     chain[0].Version,
+		Properties:  make(map[string]PropertyDefinition),
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		at := chain[i]
+		if at.Description != "" {
+			flat.Description = at.Description
+		}
+		if at.MimeType != "" {
+			flat.MimeType = at.MimeType
+		}
+		if len(at.FileExt) != 0 {
+			flat.FileExt = at.FileExt
+		}
+		for k, v := range at.Properties {
+			flat.Properties[k] = v
+		}
+	}
+	return flat, nil
+}
+
+// checkArtifact returns an error if the type of the artifact definition a is unknown
+// or if the extension of its file is not one of the extensions of its type.
+// An artifact without type is not checked.
+func (s *ServiceTemplateDefinition) checkArtifact(a ArtifactDefinition) error {
+	typ, _ := a["type"].(string)
+	if typ == "" {
+		return nil
+	}
+	at, err := s.ArtifactType(typ)
+	if err != nil {
+		return err
+	}
+	if len(at.FileExt) == 0 || a.File() == "" {
+		return nil
+	}
+	ext := strings.TrimPrefix(path.Ext(a.File()), ".")
+	for _, e := range at.FileExt {
+		if strings.EqualFold(e, ext) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the extension of %v is not one of the extensions %v of %v", a.File(), at.FileExt, typ)
+}
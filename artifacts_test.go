@@ -0,0 +1,69 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+const artifactTypesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+artifact_types:
+  my.artifacts.Bash:
+    derived_from: tosca.artifacts.Implementation
+    description: Script for the Bourne Again shell
+    mime_type: application/x-sh
+    file_ext: [ sh, bash ]
+  my.artifacts.InstallScript:
+    derived_from: my.artifacts.Bash
+topology_template:
+  node_templates:
+    app:
+      type: tosca.nodes.Root
+      artifacts:
+        install:
+          file: scripts/install.bash
+          type: my.artifacts.InstallScript
+        readme: README.md
+`
+
+func TestArtifactType(t *testing.T) {
+	s := parseString(t, artifactTypesTemplate)
+	at, err := s.ArtifactType("my.artifacts.InstallScript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at.MimeType != "application/x-sh" || len(at.FileExt) != 2 || at.Description != "Script for the Bourne Again shell" {
+		t.Fatalf("the definitions of my.artifacts.Bash should be inherited, got %+v", at)
+	}
+	if at, err := s.ArtifactType("tosca.artifacts.Implementation.Python"); err != nil || at.FileExt[0] != "py" {
+		t.Fatalf("the normative artifact types should be known, got %+v (%v)", at, err)
+	}
+	if errs := s.Validate(); len(errs) != 0 {
+		t.Fatalf("the template should be valid, got %v", errs)
+	}
+}
+
+func TestValidateArtifacts(t *testing.T) {
+	s := parseString(t, strings.NewReplacer(
+		"file: scripts/install.bash", "file: scripts/install.py",
+		"readme: README.md", "readme: { file: README.md, type: my.artifacts.Text }",
+	).Replace(artifactTypesTemplate))
+	expectErrors(t, s.Validate(),
+		[]string{"Node app: artifact install", "scripts/install.py is not one of the extensions [sh bash]"},
+		[]string{"Node app: artifact readme", "Artifact type my.artifacts.Text not found"},
+	)
+}
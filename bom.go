@@ -0,0 +1,100 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// BOMEntry is the aggregation of the node templates of a type in a bill of materials.
+// The capacities are read from the host capability of the node templates.
+type BOMEntry struct {
+	Type   string   // The node type
+	Count  int      // The number of node templates of the type
+	Nodes  []string // The sorted names of the node templates
+	CPUs   int      // The total number of CPUs
+	Memory Size     // The total memory size
+	Disk   Size     // The total disk size
+}
+
+// BOM is a bill of materials: the node templates of a topology aggregated by type, sorted by type
+type BOM []BOMEntry
+
+// BillOfMaterials aggregates the node templates of the topology by type, with the sum of the
+// number of CPUs (num_cpus), of the memory (mem_size) and of the disk (disk_size) of their host capability.
+// It fails if a capacity cannot be resolved, such as one given by an input without value.
+func (s *ServiceTemplateDefinition) BillOfMaterials() (BOM, error) {
+	entries := make(map[string]*BOMEntry)
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		e, ok := entries[node.Type]
+		if !ok {
+			e = &BOMEntry{Type: node.Type}
+			entries[node.Type] = e
+		}
+		e.Count++
+		e.Nodes = append(e.Nodes, name)
+		host, _ := node.Capabilities["host"].(map[interface{}]interface{})
+		props, _ := host["properties"].(map[interface{}]interface{})
+		for prop, v := range props {
+			v, err := s.evaluateArgument(v, name, &Context{})
+			if err != nil {
+				return nil, fmt.Errorf("Node %v: %v: %v", name, prop, err)
+			}
+			if v == nil || v == "" {
+				return nil, fmt.Errorf("Node %v: %v is unresolved: its input must be given a value", name, prop)
+			}
+			switch prop {
+			case "num_cpus":
+				n, err := strconv.Atoi(fmt.Sprint(v))
+				if err != nil {
+					return nil, fmt.Errorf("Node %v: num_cpus: %v is not an integer", name, v)
+				}
+				e.CPUs += n
+			case "mem_size", "disk_size":
+				sc := Scalar(fmt.Sprint(v))
+				if zero, ok := zeroScalar(v, KindSize); ok {
+					sc = zero
+				}
+				sv, err := sc.Evaluate()
+				if err != nil {
+					return nil, fmt.Errorf("Node %v: %v: %v", name, prop, err)
+				}
+				size, ok := sv.(Size)
+				if !ok {
+					return nil, fmt.Errorf("Node %v: %v: %v is not a size", name, prop, v)
+				}
+				if prop == "mem_size" {
+					e.Memory += size
+				} else {
+					e.Disk += size
+				}
+			}
+		}
+	}
+	types := make([]string, 0, len(entries))
+	for t := range entries {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	bom := make(BOM, len(types))
+	for i, t := range types {
+		bom[i] = *entries[t]
+	}
+	return bom, nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const bomTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    db_mem:
+      type: scalar-unit.size
+      default: 8 GB
+    cache_mem:
+      type: scalar-unit.size
+  node_templates:
+    web1:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            num_cpus: 2
+            mem_size: 2 GB
+            disk_size: 10 GB
+    web2:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            num_cpus: 2
+            mem_size: 2 GB
+            disk_size: 10 GB
+    db:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            num_cpus: 4
+            mem_size: { get_input: db_mem }
+            disk_size: 100 GB
+    storage:
+      type: tosca.nodes.BlockStorage
+      properties:
+        size: 1 TB
+`
+
+func TestBillOfMaterials(t *testing.T) {
+	s := parseString(t, bomTemplate)
+	bom, err := s.BillOfMaterials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := BOM{
+		{Type: "tosca.nodes.BlockStorage", Count: 1, Nodes: []string{"storage"}},
+		{Type: "tosca.nodes.Compute", Count: 3, Nodes: []string{"db", "web1", "web2"}, CPUs: 8, Memory: 12000000000, Disk: 120000000000},
+	}
+	if !reflect.DeepEqual(bom, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, bom)
+	}
+}
+
+func TestBillOfMaterialsZero(t *testing.T) {
+	s := parseString(t, strings.Replace(bomTemplate, "mem_size: 2 GB", "mem_size: 0", 1))
+	bom, err := s.BillOfMaterials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bom[1].Memory != 10000000000 {
+		t.Fatalf("a bare zero memory should count as 0 B, got %v", bom[1].Memory)
+	}
+}
+
+func TestBillOfMaterialsUnresolved(t *testing.T) {
+	s := parseString(t, strings.Replace(bomTemplate, "get_input: db_mem", "get_input: cache_mem", 1))
+	_, err := s.BillOfMaterials()
+	if err == nil || !strings.Contains(err.Error(), "Node db: mem_size is unresolved") {
+		t.Fatalf("expected an unresolved size error, got %v", err)
+	}
+}
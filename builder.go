@@ -0,0 +1,140 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+)
+
+// ServiceTemplateBuilder builds a service template programmatically.
+// Its methods may be chained; the first error met is kept and returned by Build and MarshalYAML.
+type ServiceTemplateBuilder struct {
+	template ServiceTemplateDefinition
+	nodes    []string // The names of the node templates in the order they were added
+	inputs   []string // The names of the inputs in the order they were added
+	err      error
+}
+
+// NewServiceTemplate returns a builder of a service template conforming to the TOSCA version
+func NewServiceTemplate(version Version) *ServiceTemplateBuilder {
+	return &ServiceTemplateBuilder{
+		template: ServiceTemplateDefinition{
+			DefinitionsVersion: version,
+			TopologyTemplate: TopologyTemplateType{
+				Inputs:        make(map[string]PropertyDefinition),
+				NodeTemplates: make(map[string]NodeTemplate),
+			},
+		},
+	}
+}
+
+// SetDescription sets the description of the service template
+func (b *ServiceTemplateBuilder) SetDescription(description string) *ServiceTemplateBuilder {
+	b.template.Description = description
+	return b
+}
+
+// AddInput adds the input name defined by def to the topology
+func (b *ServiceTemplateBuilder) AddInput(name string, def PropertyDefinition) *ServiceTemplateBuilder {
+	if b.err != nil {
+		return b
+	}
+	if _, ok := b.template.TopologyTemplate.Inputs[name]; ok {
+		b.err = fmt.Errorf("Duplicate input %v", name)
+		return b
+	}
+	b.template.TopologyTemplate.Inputs[name] = def
+	b.inputs = append(b.inputs, name)
+	return b
+}
+
+// AddNodeTemplate adds the node template name of type typ to the topology.
+// The name must not be used by another node template.
+func (b *ServiceTemplateBuilder) AddNodeTemplate(name, typ string) *ServiceTemplateBuilder {
+	if b.err != nil {
+		return b
+	}
+	if _, ok := b.template.TopologyTemplate.NodeTemplates[name]; ok {
+		b.err = fmt.Errorf("Duplicate node template %v", name)
+		return b
+	}
+	b.template.TopologyTemplate.NodeTemplates[name] = NodeTemplate{Name: name, Type: typ}
+	b.nodes = append(b.nodes, name)
+	return b
+}
+
+// SetProperty assigns value to the property key of the node template node, which must have been added before
+func (b *ServiceTemplateBuilder) SetProperty(node, key string, value interface{}) *ServiceTemplateBuilder {
+	if b.err != nil {
+		return b
+	}
+	n, ok := b.template.TopologyTemplate.NodeTemplates[node]
+	if !ok {
+		b.err = fmt.Errorf("Node template %v not found", node)
+		return b
+	}
+	if n.Properties == nil {
+		n.Properties = make(map[string]PropertyAssignment)
+	}
+	n.Properties[key] = PropertyAssignment{"value": []interface{}{value}}
+	b.template.TopologyTemplate.NodeTemplates[node] = n
+	return b
+}
+
+// Build returns the service template, or the first error met while building it
+func (b *ServiceTemplateBuilder) Build() (*ServiceTemplateDefinition, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	t := b.template
+	return &t, nil
+}
+
+// MarshalYAML returns the TOSCA document of the service template, with the inputs
+// and the node templates in the order they were added
+func (b *ServiceTemplateBuilder) MarshalYAML() (interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	doc := yaml.MapSlice{{Key: "tosca_definitions_version", Value: b.template.DefinitionsVersion}}
+	if b.template.Description != "" {
+		doc = append(doc, yaml.MapItem{Key: "description", Value: b.template.Description})
+	}
+	var topology yaml.MapSlice
+	if len(b.inputs) > 0 {
+		inputs := make(yaml.MapSlice, len(b.inputs))
+		for i, name := range b.inputs {
+			inputs[i] = yaml.MapItem{Key: name, Value: b.template.TopologyTemplate.Inputs[name]}
+		}
+		topology = append(topology, yaml.MapItem{Key: "inputs", Value: inputs})
+	}
+	nodes := make(yaml.MapSlice, len(b.nodes))
+	for i, name := range b.nodes {
+		n := b.template.TopologyTemplate.NodeTemplates[name]
+		node := yaml.MapSlice{{Key: "type", Value: n.Type}}
+		if len(n.Properties) > 0 {
+			props := make(map[string]interface{}, len(n.Properties))
+			for key, pa := range n.Properties {
+				props[key] = pa["value"][0]
+			}
+			node = append(node, yaml.MapItem{Key: "properties", Value: props})
+		}
+		nodes[i] = yaml.MapItem{Key: name, Value: node}
+	}
+	topology = append(topology, yaml.MapItem{Key: "node_templates", Value: nodes})
+	return append(doc, yaml.MapItem{Key: "topology_template", Value: topology}), nil
+}
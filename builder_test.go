@@ -0,0 +1,77 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"gopkg.in/yaml.v2"
+	"testing"
+)
+
+func TestServiceTemplateBuilder(t *testing.T) {
+	b := NewServiceTemplate("tosca_simple_yaml_1_0").
+		SetDescription("A web server on a compute").
+		AddInput("port", PropertyDefinition{Type: "integer", Default: "8080"}).
+		AddNodeTemplate("server", "tosca.nodes.Compute").
+		AddNodeTemplate("web", "tosca.nodes.WebServer").
+		SetProperty("web", "component_version", "2.4").
+		SetProperty("web", "listen_port", map[string]interface{}{"get_input": "port"})
+	out, err := yaml.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := parseString(t, string(out))
+	if s.Description != "A web server on a compute" {
+		t.Errorf("bad description %q", s.Description)
+	}
+	if in := s.TopologyTemplate.Inputs["port"]; in.Type != "integer" || in.Default != "8080" {
+		t.Errorf("bad input port %+v", in)
+	}
+	built, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.TopologyTemplate.NodeTemplates) != 2 {
+		t.Fatalf("expected 2 node templates, got %v", len(s.TopologyTemplate.NodeTemplates))
+	}
+	for name, n := range built.TopologyTemplate.NodeTemplates {
+		if s.TopologyTemplate.NodeTemplates[name].Type != n.Type {
+			t.Errorf("node %v: expected type %v, got %v", name, n.Type, s.TopologyTemplate.NodeTemplates[name].Type)
+		}
+	}
+	web := s.TopologyTemplate.NodeTemplates["web"]
+	if v := web.Properties["component_version"]["value"]; len(v) != 1 || v[0] != "2.4" {
+		t.Errorf("bad component_version %v", web.Properties["component_version"])
+	}
+	if v := web.Properties["listen_port"]["get_input"]; len(v) != 1 || v[0] != "port" {
+		t.Errorf("bad listen_port %v", web.Properties["listen_port"])
+	}
+}
+
+func TestServiceTemplateBuilderErrors(t *testing.T) {
+	b := NewServiceTemplate("tosca_simple_yaml_1_0").
+		AddNodeTemplate("server", "tosca.nodes.Compute").
+		AddNodeTemplate("server", "tosca.nodes.Compute")
+	if _, err := b.Build(); err == nil || err.Error() != "Duplicate node template server" {
+		t.Fatalf("expected a duplicate node error, got %v", err)
+	}
+	if _, err := yaml.Marshal(b); err == nil {
+		t.Fatal("marshaling an invalid template should fail")
+	}
+	b = NewServiceTemplate("tosca_simple_yaml_1_0").SetProperty("web", "port", 80)
+	if _, err := b.Build(); err == nil || err.Error() != "Node template web not found" {
+		t.Fatalf("expected a missing node error, got %v", err)
+	}
+}
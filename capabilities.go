@@ -15,14 +15,20 @@ limitations under the License.
 */
 package toscalib
 
+import (
+	"fmt"
+)
+
 // CapabilityDefinition TODO: Appendix 6.1
 type CapabilityDefinition struct {
-	Type             string                `yaml:"type" json:"type"`                                    //  The required name of the Capability Type the capability definition is based upon.
-	Description      string                `yaml:"description,omitempty" jsson:"description,omitempty"` // The optional description of the Capability definition.
-	Properties       []PropertyDefinition  `yaml:"properties,omitempty" json:"properties,omitempty"`    //  An optional list of property definitions for the Capability definition.
-	Attributes       []AttributeDefinition `yaml:"attributes" json:"attributes"`                        // An optional list of attribute definitions for the Capability definition.
-	ValidSourceTypes []string              `yaml:"valid_source_types" json:"valid_source_types"`        // A`n optional list of one or more valid names of Node Types that are supported as valid sources of any relationship established to the declared Capability Type.
-	Occurences       []string              `yaml:"occurences" json:"occurences"`
+	Type             string                         `yaml:"type" json:"type"`                                    //  The required name of the Capability Type the capability definition is based upon.
+	Description      string                         `yaml:"description,omitempty" jsson:"description,omitempty"` // The optional description of the Capability definition.
+	Properties       map[string]PropertyDefinition  `yaml:"properties,omitempty" json:"properties,omitempty"`    //  An optional list of property definitions for the Capability definition.
+	Attributes       map[string]AttributeDefinition `yaml:"attributes" json:"attributes"`                        // An optional list of attribute definitions for the Capability definition.
+	ValidSourceTypes []string                       `yaml:"valid_source_types" json:"valid_source_types"`        // A`n optional list of one or more valid names of Node Types that are supported as valid sources of any relationship established to the declared Capability Type.
+	Occurrences      ToscaRange                     `yaml:"occurrences,omitempty" json:"occurrences,omitempty"`  // The optional minimum and maximum occurrences for the capability, [1, 1] if they are omitted.
+
+	occurrencesDeclared bool // True if the occurrences are written in the definition rather than defaulted
 }
 
 // UnmarshalYAML is used to match both Simple Notation Example and Full Notation Example
@@ -32,16 +38,17 @@ func (c *CapabilityDefinition) UnmarshalYAML(unmarshal func(interface{}) error)
 	err := unmarshal(&cas)
 	if err == nil {
 		c.Type = cas
+		c.Occurrences = defaultOccurrences
 		return nil
 	}
 	// If error, try the full struct
 	type cap struct {
-		Type             string                `yaml:"type" json:"type"`                                    //  The required name of the Capability Type the capability definition is based upon.
-		Description      string                `yaml:"description,omitempty" jsson:"description,omitempty"` // The optional description of the Capability definition.
-		Properties       []PropertyDefinition  `yaml:"properties,omitempty" json:"properties,omitempty"`    //  An optional list of property definitions for the Capability definition.
-		Attributes       []AttributeDefinition `yaml:"attributes" json:"attributes"`                        // An optional list of attribute definitions for the Capability definition.
-		ValidSourceTypes []string              `yaml:"valid_source_types" json:"valid_source_types"`        // A`n optional list of one or more valid names of Node Types that are supported as valid sources of any relationship established to the declared Capability Type.
-		Occurences       []string              `yaml:"occurences" json:"occurences"`
+		Type             string                         `yaml:"type" json:"type"`                                    //  The required name of the Capability Type the capability definition is based upon.
+		Description      string                         `yaml:"description,omitempty" jsson:"description,omitempty"` // The optional description of the Capability definition.
+		Properties       map[string]PropertyDefinition  `yaml:"properties,omitempty" json:"properties,omitempty"`    //  An optional list of property definitions for the Capability definition.
+		Attributes       map[string]AttributeDefinition `yaml:"attributes" json:"attributes"`                        // An optional list of attribute definitions for the Capability definition.
+		ValidSourceTypes []string                       `yaml:"valid_source_types" json:"valid_source_types"`        // A`n optional list of one or more valid names of Node Types that are supported as valid sources of any relationship established to the declared Capability Type.
+		Occurrences      *ToscaRange                    `yaml:"occurrences,omitempty" json:"occurrences,omitempty"`
 	}
 	var ca cap
 	err = unmarshal(&ca)
@@ -52,7 +59,11 @@ func (c *CapabilityDefinition) UnmarshalYAML(unmarshal func(interface{}) error)
 	c.Description = ca.Description
 	c.Properties = ca.Properties
 	c.Attributes = ca.Attributes
-	c.Occurences = ca.Occurences
+	c.Occurrences = defaultOccurrences
+	if ca.Occurrences != nil {
+		c.Occurrences = *ca.Occurrences
+		c.occurrencesDeclared = true
+	}
 	c.ValidSourceTypes = ca.ValidSourceTypes
 
 	return nil
@@ -69,3 +80,160 @@ type CapabilityType struct {
 	Attributes   map[string]AttributeDefinition `yaml:"attributes,omitempty" json:"attributes,omitempty"` // An optional list of attribute definitions for the Node Type.
 	ValidSources []string                       `yaml:"valid_source_types,omitempty" json:"valid_source_types"`
 }
+
+// CapabilityType returns the capability type named name with the properties,
+// attributes and valid source types inherited from its ancestors
+func (s *ServiceTemplateDefinition) CapabilityType(name string) (CapabilityType, error) {
+	var chain []CapabilityType
+	visited := make(map[string]bool)
+	for n := name; n != ""; {
+		if visited[n] {
+			return CapabilityType{}, fmt.Errorf("Capability type %v: cyclic derivation through %v", name, n)
+		}
+		visited[n] = true
+		ct, ok := s.CapabilityTypes[n]
+		if !ok {
+			return CapabilityType{}, fmt.Errorf("Capability type %v not found", n)
+		}
+		chain = append(chain, ct)
+		n = ct.DerivedFrom
+	}
+	flat := CapabilityType{
+		DerivedFrom: chain[0].DerivedFrom,
+		Version:     chain[0].Version,
+		Properties:  make(map[string]PropertyDefinition),
+		Attributes:  make(map[string]AttributeDefinition),
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		ct := chain[i]
+		if ct.Description != "" {
+			flat.Description = ct.Description
+		}
+		for k, v := range ct.Properties {
+			flat.Properties[k] = v
+		}
+		for k, v := range ct.Attributes {
+			flat.Attributes[k] = v
+		}
+		if len(ct.ValidSources) != 0 {
+			flat.ValidSources = ct.ValidSources
+		}
+	}
+	return flat, nil
+}
+
+// capabilityAttributes returns the attribute definitions of the capability definition capDef:
+// the ones of its capability type refined by the ones of the definition
+func (s *ServiceTemplateDefinition) capabilityAttributes(capDef CapabilityDefinition) (map[string]AttributeDefinition, error) {
+	ct, err := s.CapabilityType(capDef.Type)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range capDef.Attributes {
+		ct.Attributes[k] = v
+	}
+	return ct.Attributes, nil
+}
+
+// GetCapabilityAttribute returns the value of the attribute of the capability of the node template node,
+// as referenced by get_attribute: [ node, capability, attribute ].
+// The value assigned by the node template takes precedence over the default value of the attribute definition.
+func (s *ServiceTemplateDefinition) GetCapabilityAttribute(node, capability, attribute string) (interface{}, error) {
+	nt := s.GetNodeTemplate(node)
+	if nt == nil {
+		return nil, fmt.Errorf("Node template %v not found", node)
+	}
+	typ, err := s.FlattenNodeType(nt.Type)
+	if err != nil {
+		return nil, err
+	}
+	capDef, ok := typ.Capabilities[capability]
+	if !ok {
+		return nil, fmt.Errorf("Node %v: capability %v is not defined by %v", node, capability, nt.Type)
+	}
+	attrs, err := s.capabilityAttributes(capDef)
+	if err != nil {
+		return nil, fmt.Errorf("Node %v: capability %v: %v", node, capability, err)
+	}
+	def, ok := attrs[attribute]
+	if !ok {
+		return nil, fmt.Errorf("Node %v: capability %v: attribute %v is not defined by %v", node, capability, attribute, capDef.Type)
+	}
+	assignment, _ := nt.Capabilities[capability].(map[interface{}]interface{})
+	values, _ := assignment["attributes"].(map[interface{}]interface{})
+	if v, ok := values[attribute]; ok {
+		return v, nil
+	}
+	return def.Default, nil
+}
+
+// capabilityProperties returns the property definitions of the capability definition capDef:
+// the ones of its capability type refined by the ones of the definition
+func (s *ServiceTemplateDefinition) capabilityProperties(capDef CapabilityDefinition) (map[string]PropertyDefinition, error) {
+	ct, err := s.CapabilityType(capDef.Type)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range capDef.Properties {
+		ct.Properties[k] = v
+	}
+	return ct.Properties, nil
+}
+
+// GetCapabilityProperty returns the value of the property of the capability of the node template node,
+// as referenced by get_property: [ node, capability, property ].
+// The value assigned by the node template takes precedence over the default value of the property definition;
+// a function assigned to the property is returned as a PropertyAssignment, any other map is returned as is.
+func (s *ServiceTemplateDefinition) GetCapabilityProperty(node, capability, property string) (interface{}, error) {
+	nt := s.GetNodeTemplate(node)
+	if nt == nil {
+		return nil, fmt.Errorf("Node template %v not found", node)
+	}
+	typ, err := s.FlattenNodeType(nt.Type)
+	if err != nil {
+		return nil, err
+	}
+	capDef, ok := typ.Capabilities[capability]
+	if !ok {
+		return nil, fmt.Errorf("Node %v: capability %v is not defined by %v", node, capability, nt.Type)
+	}
+	props, err := s.capabilityProperties(capDef)
+	if err != nil {
+		return nil, fmt.Errorf("Node %v: capability %v: %v", node, capability, err)
+	}
+	def, ok := props[property]
+	if !ok {
+		return nil, fmt.Errorf("Node %v: capability %v: property %v is not defined by %v", node, capability, property, capDef.Type)
+	}
+	assignment, _ := nt.Capabilities[capability].(map[interface{}]interface{})
+	values, _ := assignment["properties"].(map[interface{}]interface{})
+	v, ok := values[property]
+	if !ok {
+		return def.Default, nil
+	}
+	function, ok := v.(map[interface{}]interface{})
+	if !ok || len(function) != 1 {
+		return v, nil
+	}
+	pa := make(PropertyAssignment, 1)
+	for name, args := range function {
+		if !intrinsicFunctions[fmt.Sprint(name)] {
+			// A map value, not a function call
+			return v, nil
+		}
+		if list, ok := args.([]interface{}); ok {
+			pa[fmt.Sprint(name)] = list
+		} else {
+			pa[fmt.Sprint(name)] = []interface{}{args}
+		}
+	}
+	return pa, nil
+}
+
+// isCapabilityType returns true if the capability type name is ancestor or derives from it
+func (s *ServiceTemplateDefinition) isCapabilityType(name, ancestor string) bool {
+	return derivesFrom(name, ancestor, func(n string) (string, bool) {
+		ct, ok := s.CapabilityTypes[n]
+		return ct.DerivedFrom, ok
+	})
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const capabilityTypesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+capability_types:
+  my.capabilities.Storage:
+    derived_from: tosca.capabilities.Root
+    valid_source_types: [ tosca.nodes.SoftwareComponent ]
+    properties:
+      size:
+        type: scalar-unit.size
+  my.capabilities.FastStorage:
+    derived_from: my.capabilities.Storage
+    properties:
+      iops:
+        type: integer
+topology_template:
+  node_templates:
+`
+
+func TestCapabilityType(t *testing.T) {
+	s := parseString(t, capabilityTypesTemplate)
+	ct, err := s.CapabilityType("my.capabilities.Storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ct.Properties["size"]; !ok {
+		t.Fatal("property size is missing")
+	}
+	if len(ct.ValidSources) != 1 || ct.ValidSources[0] != "tosca.nodes.SoftwareComponent" {
+		t.Fatalf("bad valid source types %v", ct.ValidSources)
+	}
+	ct, err = s.CapabilityType("my.capabilities.FastStorage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct.DerivedFrom != "my.capabilities.Storage" {
+		t.Fatalf("bad parent %v", ct.DerivedFrom)
+	}
+	for _, p := range []string{"size", "iops"} {
+		if _, ok := ct.Properties[p]; !ok {
+			t.Fatalf("property %v is missing from the flattened type", p)
+		}
+	}
+	if len(ct.ValidSources) != 1 {
+		t.Fatalf("valid source types should be inherited, got %v", ct.ValidSources)
+	}
+	if _, err := s.CapabilityType("my.capabilities.Unknown"); err == nil {
+		t.Fatal("an unknown capability type should not be found")
+	}
+}
+
+const capabilityAttributesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+capability_types:
+  my.capabilities.Queue:
+    derived_from: tosca.capabilities.Root
+    attributes:
+      endpoint_url:
+        type: string
+      depth:
+        type: integer
+        default: 0
+node_types:
+  my.nodes.Broker:
+    derived_from: tosca.nodes.Root
+    capabilities:
+      queue:
+        type: my.capabilities.Queue
+        attributes:
+          consumers:
+            type: integer
+            default: 1
+topology_template:
+  node_templates:
+    broker:
+      type: my.nodes.Broker
+      capabilities:
+        queue:
+          attributes:
+            endpoint_url: amqp://broker:5672
+  outputs:
+    queue_consumers:
+      value: { get_attribute: [ broker, queue, consumers ] }
+`
+
+func TestCapabilityAttributes(t *testing.T) {
+	s := parseString(t, capabilityAttributesTemplate)
+	if errs := s.Validate(); len(errs) != 0 {
+		t.Fatalf("the template should be valid, got %v", errs)
+	}
+	for attr, expected := range map[string]interface{}{
+		"endpoint_url": "amqp://broker:5672",
+		"depth":        0,
+		"consumers":    1,
+	} {
+		v, err := s.GetCapabilityAttribute("broker", "queue", attr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != expected {
+			t.Errorf("attribute %v: expected %v, got %v", attr, expected, v)
+		}
+	}
+	if _, err := s.GetCapabilityAttribute("broker", "queue", "latency"); err == nil {
+		t.Error("an undefined attribute should not be resolved")
+	}
+}
+
+func TestValidateCapabilityAttributes(t *testing.T) {
+	doc := strings.Replace(capabilityAttributesTemplate, "endpoint_url: amqp://broker:5672", "latency: 10", 1)
+	doc = strings.Replace(doc, "default: 0", "default: empty", 1)
+	s := parseString(t, doc)
+	expectErrors(t, s.Validate(),
+		[]string{"Capability type my.capabilities.Queue", "attribute depth", "invalid default empty"},
+		[]string{"Node broker", "capability queue", "attribute latency is not defined"},
+	)
+}
+
+const capabilityGetPropertyTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+capability_types:
+  my.capabilities.Queue:
+    derived_from: tosca.capabilities.Root
+    properties:
+      port:
+        type: integer
+      protocol:
+        type: string
+        default: amqp
+      options:
+        type: map
+        entry_schema:
+          type: boolean
+topology_template:
+  inputs:
+    broker_port:
+      type: integer
+      default: 5672
+  node_templates:
+    broker:
+      type: my.nodes.Broker
+      capabilities:
+        queue:
+          properties:
+            port: { get_input: broker_port }
+            options: { durable: true }
+node_types:
+  my.nodes.Broker:
+    derived_from: tosca.nodes.Root
+    capabilities:
+      queue:
+        type: my.capabilities.Queue
+`
+
+func TestGetPropertyOfCapability(t *testing.T) {
+	s := parseString(t, capabilityGetPropertyTemplate)
+	for prop, expected := range map[string]interface{}{
+		"port":     "5672",
+		"protocol": "amqp",
+	} {
+		v, err := s.EvaluateStatementWithOptions(PA{PA: PropertyAssignment{"get_property": {"broker", "queue", prop}}}, EvaluateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != expected {
+			t.Errorf("property %v: expected %v, got %v", prop, expected, v)
+		}
+	}
+	for _, tc := range []struct {
+		args []interface{}
+		err  string
+	}{
+		{[]interface{}{"broker", "topic", "port"}, "Node broker: capability topic is not defined by my.nodes.Broker"},
+		{[]interface{}{"broker", "queue", "vhost"}, "Node broker: capability queue: property vhost is not defined by my.capabilities.Queue"},
+		{[]interface{}{"db", "queue", "port"}, "Node template db not found"},
+	} {
+		_, err := s.EvaluateStatement(PA{PA: PropertyAssignment{"get_property": tc.args}})
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("get_property %v: expected the error %q, got %v", tc.args, tc.err, err)
+		}
+	}
+	// A map value is not a function call
+	v, err := s.GetCapabilityProperty("broker", "queue", "options")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, map[interface{}]interface{}{"durable": true}) {
+		t.Errorf("expected the map value, got %#v", v)
+	}
+}
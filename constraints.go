@@ -15,16 +15,42 @@ limitations under the License.
 */
 package toscalib
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
 type Value string
 
 //Constraints is an array of ConstraintClause
 type Constraints []ConstraintClause
 
-// IsValid returns true if the Value is valid against the Constraints
+// IsValid returns true if the Value is valid against the Constraints.
+// The error explains why the value is not valid.
 func (c Constraints) IsValid(v Value) (bool, error) {
+	if err := c.check(string(v)); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
+// check returns an error if v does not satisfy all the constraint clauses.
+// The error lists every violated clause.
+func (c Constraints) check(v interface{}) error {
+	var violations []string
+	for _, clause := range c {
+		if err := clause.check(v); err != nil {
+			violations = append(violations, err.Error())
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%v", strings.Join(violations, "; "))
+}
+
 // ConstraintClause definition as described in Appendix 5.2.
 // This is a map where the index is a string that may have a value in
 // {"equal","greater_than", ...} (see Appendix 5.2) a,s value is an interface
@@ -36,9 +62,199 @@ type ConstraintClause struct {
 }
 
 // Evaluate the constraint and return a boolean
-func (constraint *ConstraintClause) Evaluate(interface{}) bool { return true }
+func (constraint *ConstraintClause) Evaluate(v interface{}) bool { return constraint.check(v) == nil }
+
+// check returns an error if v does not satisfy the constraint.
+// The bounds of an in_range constraint on a scalar may be plain numbers, they are then
+// expressed in the base unit of the scalar (B, Hz or ns).
+func (constraint *ConstraintClause) check(v interface{}) error {
+	// order applies cmp to the comparison of v with the bound b
+	order := func(b interface{}, cmp func(int) bool) (bool, error) {
+		c, ordered := compareValues(v, b)
+		if !ordered {
+			return false, fmt.Errorf("Cannot compare %v with %v", v, b)
+		}
+		return cmp(c), nil
+	}
+	var ok bool
+	var err error
+	switch constraint.Operator {
+	case "equal":
+		c, _ := compareValues(v, constraint.Values)
+		ok = c == 0
+	case "greater_than":
+		ok, err = order(constraint.Values, func(c int) bool { return c > 0 })
+	case "greater_or_equal":
+		ok, err = order(constraint.Values, func(c int) bool { return c >= 0 })
+	case "less_than":
+		ok, err = order(constraint.Values, func(c int) bool { return c < 0 })
+	case "less_or_equal":
+		ok, err = order(constraint.Values, func(c int) bool { return c <= 0 })
+	case "in_range":
+		bounds, isList := constraint.Values.([]interface{})
+		if !isList || len(bounds) != 2 {
+			return fmt.Errorf("in_range expects 2 values, got %v", constraint.Values)
+		}
+		v = zeroOfBound(v, bounds[0])
+		ok, err = order(scalarBound(v, bounds[0]), func(c int) bool { return c >= 0 })
+		if ok && bounds[1] != "UNBOUNDED" {
+			ok, err = order(scalarBound(v, bounds[1]), func(c int) bool { return c <= 0 })
+		}
+	case "valid_values":
+		values, isList := constraint.Values.([]interface{})
+		if !isList {
+			return fmt.Errorf("valid_values expects a list, got %v", constraint.Values)
+		}
+		for _, value := range values {
+			if c, _ := compareValues(v, value); c == 0 {
+				ok = true
+				break
+			}
+		}
+	case "length", "min_length", "max_length":
+		var length int
+		length, err = valueLength(v)
+		if err != nil {
+			break
+		}
+		expected, convErr := strconv.Atoi(fmt.Sprint(constraint.Values))
+		if convErr != nil {
+			return fmt.Errorf("%v expects an integer, got %v", constraint.Operator, constraint.Values)
+		}
+		switch constraint.Operator {
+		case "length":
+			ok = length == expected
+		case "min_length":
+			ok = length >= expected
+		default:
+			ok = length <= expected
+		}
+	case "pattern":
+		var re *regexp.Regexp
+		re, err = regexp.Compile("^(?:" + fmt.Sprint(constraint.Values) + ")$")
+		if err == nil {
+			ok = re.MatchString(fmt.Sprint(v))
+		}
+	default:
+		return fmt.Errorf("Unknown constraint %v", constraint.Operator)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%v does not satisfy the constraint %v %v", v, constraint.Operator, constraint.Values)
+	}
+	return nil
+}
+
+// scalarBound returns the bound of a range in the base unit of v if v is a scalar and bound a plain number:
+// [0, 1073741824] is read as [0 B, 1073741824 B] for a scalar-unit.size value.
+// Any other bound is returned unchanged.
+func scalarBound(v, bound interface{}) interface{} {
+	_, kind, err := Scalar(fmt.Sprint(v)).normalize()
+	if err != nil {
+		return bound
+	}
+	if _, err := strconv.ParseFloat(fmt.Sprint(bound), 64); err != nil {
+		return bound
+	}
+	return fmt.Sprintf("%v %v", bound, baseUnits[kind])
+}
+
+// zeroOfBound returns the zero of the kind of the scalar bound if v is the number zero written without unit,
+// so that 0 is within [ 0 MB, 1 GB ]. Any other value is returned unchanged.
+func zeroOfBound(v, bound interface{}) interface{} {
+	kind, err := Scalar(fmt.Sprint(bound)).Kind()
+	if err != nil {
+		return v
+	}
+	if zero, ok := zeroScalar(v, kind); ok {
+		return zero
+	}
+	return v
+}
+
+// compareValues compares a and b, which may be scalars, numbers, booleans or strings.
+// The values read from YAML as strings are coerced to the type of the other value.
+// It returns a negative number if a < b, 0 if they are equal and a positive number if a > b.
+// ordered is false if a and b cannot be ordered, such as strings or scalars of different types;
+// the comparison then only tells whether they are equal.
+func compareValues(a, b interface{}) (c int, ordered bool) {
+	sa, sb := fmt.Sprint(a), fmt.Sprint(b)
+	va, ka, errA := Scalar(sa).normalize()
+	vb, kb, errB := Scalar(sb).normalize()
+	if errA == nil || errB == nil {
+		if errA != nil || errB != nil || ka != kb {
+			return 1, false
+		}
+		return compareFloats(va, vb), true
+	}
+	ia, errA := strconv.ParseInt(sa, 10, 64)
+	ib, errB := strconv.ParseInt(sb, 10, 64)
+	if errA == nil && errB == nil {
+		// Compare the integers exactly, beyond the precision of a float64
+		return compareInts(ia, ib), true
+	}
+	fa, errA := strconv.ParseFloat(sa, 64)
+	fb, errB := strconv.ParseFloat(sb, 64)
+	if errA == nil && errB == nil {
+		return compareFloats(fa, fb), true
+	}
+	ba, okA := booleans[sa]
+	bb, okB := booleans[sb]
+	if okA && okB {
+		sa, sb = strconv.FormatBool(ba), strconv.FormatBool(bb)
+	}
+	if sa == sb {
+		return 0, false
+	}
+	return 1, false
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// booleans holds the YAML spellings of the booleans
+var booleans = map[string]bool{
+	"true":  true,
+	"True":  true,
+	"TRUE":  true,
+	"false": false,
+	"False": false,
+	"FALSE": false,
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// valueLength returns the length of a string, a list or a map
+func valueLength(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case string:
+		return len(v), nil
+	case []interface{}:
+		return len(v), nil
+	case map[interface{}]interface{}:
+		return len(v), nil
+	}
+	return 0, fmt.Errorf("%v has no length", v)
+}
 
-// UnmarshalYAML TODO: implement the Mashaler YAML interface for the constraint type
+// UnmarshalYAML reads a constraint clause written as a single-key map of the operator to its values
 func (constraint *ConstraintClause) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var c map[string]interface{}
 	err := unmarshal(&c)
@@ -52,7 +268,6 @@ func (constraint *ConstraintClause) UnmarshalYAML(unmarshal func(interface{}) er
 		v = val
 
 	}
-	constraint = &ConstraintClause{o, v}
-	//*constraint = ConstraintClause(c)
+	*constraint = ConstraintClause{o, v}
 	return nil
 }
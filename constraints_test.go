@@ -0,0 +1,132 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstraintClauseEvaluate(t *testing.T) {
+	tests := []struct {
+		c     ConstraintClause
+		v     interface{}
+		valid bool
+	}{
+		{ConstraintClause{"equal", "tcp"}, "tcp", true},
+		{ConstraintClause{"equal", "tcp"}, "udp", false},
+		{ConstraintClause{"greater_than", 1}, 2, true},
+		{ConstraintClause{"greater_than", 1}, 1, false},
+		{ConstraintClause{"greater_or_equal", "1 GB"}, "1000 MB", true},
+		{ConstraintClause{"less_than", "1 GHz"}, "1500 MHz", false},
+		{ConstraintClause{"less_or_equal", 1.5}, "1.5", true},
+		{ConstraintClause{"in_range", []interface{}{1, 4}}, 4, true},
+		{ConstraintClause{"in_range", []interface{}{1, 4}}, 5, false},
+		{ConstraintClause{"in_range", []interface{}{1, "UNBOUNDED"}}, 5000, true},
+		{ConstraintClause{"valid_values", []interface{}{"small", "large"}}, "large", true},
+		{ConstraintClause{"valid_values", []interface{}{"small", "large"}}, "medium", false},
+		{ConstraintClause{"length", 3}, "abc", true},
+		{ConstraintClause{"min_length", 2}, []interface{}{1}, false},
+		{ConstraintClause{"max_length", 2}, "ab", true},
+		{ConstraintClause{"pattern", "[a-z]+"}, "abc", true},
+		{ConstraintClause{"pattern", "[a-z]+"}, "abc1", false},
+		{ConstraintClause{"greater_than", "1 GB"}, "2 GHz", false},
+		{ConstraintClause{"greater_than", "abc"}, "abd", false},
+		{ConstraintClause{"unknown", 1}, 1, false},
+		{ConstraintClause{"greater_than", 0}, "8080", true},
+		{ConstraintClause{"greater_than", 0}, "0", false},
+		{ConstraintClause{"greater_than", 0}, -1, false},
+		{ConstraintClause{"greater_than", 9007199254740992}, "9007199254740993", true},
+		{ConstraintClause{"equal", true}, true, true},
+		{ConstraintClause{"equal", true}, "True", true},
+		{ConstraintClause{"equal", true}, "false", false},
+		{ConstraintClause{"equal", false}, 0, false},
+		{ConstraintClause{"valid_values", []interface{}{true}}, "true", true},
+		{ConstraintClause{"valid_values", []interface{}{true}}, false, false},
+		{ConstraintClause{"greater_than", false}, true, false},
+	}
+	for _, test := range tests {
+		if valid := test.c.Evaluate(test.v); valid != test.valid {
+			t.Errorf("%v %v %v: expected %v, got %v", test.v, test.c.Operator, test.c.Values, test.valid, valid)
+		}
+	}
+}
+
+func TestConstraintsIsValid(t *testing.T) {
+	c := Constraints{{"min_length", 2}, {"max_length", 4}}
+	if ok, err := c.IsValid("abc"); !ok || err != nil {
+		t.Errorf("abc should be valid, got %v", err)
+	}
+	if ok, err := c.IsValid("abcde"); ok || err == nil {
+		t.Error("abcde should not be valid")
+	}
+}
+
+func TestConstraintsReportEveryViolation(t *testing.T) {
+	c := Constraints{{"greater_than", 0}, {"less_than", 100}, {"valid_values", []interface{}{10, 20, 50}}}
+	_, err := c.IsValid("150")
+	if err == nil {
+		t.Fatal("150 should not be valid")
+	}
+	for _, clause := range []string{"less_than", "valid_values"} {
+		if !strings.Contains(err.Error(), clause) {
+			t.Errorf("the violation of %v is not reported in %v", clause, err)
+		}
+	}
+	if strings.Contains(err.Error(), "greater_than") {
+		t.Errorf("the satisfied greater_than constraint is reported in %v", err)
+	}
+}
+
+func TestInRangeScalarPlainBounds(t *testing.T) {
+	c := ConstraintClause{"in_range", []interface{}{0, 1073741824}}
+	for v, valid := range map[string]bool{
+		"512 MiB":      true,
+		"1 GiB":        true,
+		"1073741825 B": false,
+		"2 GB":         false,
+	} {
+		if c.Evaluate(v) != valid {
+			t.Errorf("%v in_range [0, 1073741824]: expected %v", v, valid)
+		}
+	}
+	if !(&ConstraintClause{"in_range", []interface{}{"1 MB", 1073741824}}).Evaluate("1 GB") {
+		t.Error("a scalar bound may be mixed with a plain bound")
+	}
+	for _, bounds := range [][]interface{}{{"zero", 1073741824}, {0, "1 GHz"}, {0, "1 parsec"}} {
+		c := ConstraintClause{"in_range", bounds}
+		if err := c.check("1 GiB"); err == nil || !strings.Contains(err.Error(), "Cannot compare") {
+			t.Errorf("in_range %v should fail on malformed bounds, got %v", bounds, err)
+		}
+	}
+}
+
+func TestInRangeScalarZero(t *testing.T) {
+	for _, zero := range []Scalar{"0 MB", "0 B", "0.0 GiB", "0 PB", "0 THz", "0 d", "0 ns"} {
+		if v, _, err := zero.normalize(); err != nil || v != 0 {
+			t.Errorf("%v should normalize to exactly 0, got %v (%v)", zero, v, err)
+		}
+	}
+	c := ConstraintClause{"in_range", []interface{}{"0 MB", "1 GB"}}
+	for _, v := range []interface{}{"0 MB", "0 GiB", 0, "0"} {
+		if err := c.check(v); err != nil {
+			t.Errorf("%v should be in [ 0 MB, 1 GB ], got %v", v, err)
+		}
+	}
+	if c.Evaluate(5) {
+		t.Error("a number other than zero should still miss its unit")
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dependencies returns the sorted names of the node templates the node template named node
+// depends on, directly or transitively, through DependsOn or HostedOn relationships.
+// It fails if the dependencies are cyclic.
+func (s *ServiceTemplateDefinition) Dependencies(node string) ([]string, error) {
+	deps := make(map[string]bool)
+	if err := s.dependencies(node, deps, nil); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// dependencies adds the dependencies of node to deps; path holds the nodes being visited
+func (s *ServiceTemplateDefinition) dependencies(node string, deps map[string]bool, path []string) error {
+	for i, n := range path {
+		if n == node {
+			return fmt.Errorf("Cyclic dependency: %v", strings.Join(append(path[i:], node), " -> "))
+		}
+	}
+	matches, err := s.MatchRequirements(node)
+	if err != nil {
+		return err
+	}
+	path = append(path, node)
+	for _, m := range matches {
+		if !s.isRelationshipType(m.Relationship, "tosca.relationships.DependsOn") && !s.isRelationshipType(m.Relationship, "tosca.relationships.HostedOn") {
+			continue
+		}
+		if deps[m.Target] {
+			continue
+		}
+		if err := s.dependencies(m.Target, deps, path); err != nil {
+			return err
+		}
+		deps[m.Target] = true
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dependenciesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    db:
+      type: tosca.nodes.DBMS
+      requirements:
+        - host: server
+    app:
+      type: tosca.nodes.SoftwareComponent
+      requirements:
+        - host: server
+        - dependency: db
+    web:
+      type: tosca.nodes.Root
+      requirements:
+        - dependency: app
+        - dependency: db
+    loop_a:
+      type: tosca.nodes.Root
+      requirements:
+        - dependency: loop_b
+    loop_b:
+      type: tosca.nodes.Root
+      requirements:
+        - dependency: loop_a
+`
+
+func TestDependencies(t *testing.T) {
+	s := parseString(t, dependenciesTemplate)
+	for node, expected := range map[string][]string{
+		"server": {},
+		"db":     {"server"},
+		"app":    {"db", "server"},
+		"web":    {"app", "db", "server"},
+	} {
+		deps, err := s.Dependencies(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(deps, expected) {
+			t.Errorf("%v: expected %v, got %v", node, expected, deps)
+		}
+	}
+}
+
+func TestDependenciesCycle(t *testing.T) {
+	s := parseString(t, dependenciesTemplate)
+	_, err := s.Dependencies("loop_a")
+	if err == nil || !strings.Contains(err.Error(), "loop_a -> loop_b -> loop_a") {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// MaxValueDepth returns the greatest nesting depth of the values assigned in the topology template:
+// the properties, capabilities and operation inputs of the node templates, the properties of the
+// relationship templates and policies, and the outputs.
+// A scalar value has a depth of 0, each enclosing list or map adds 1.
+func (s *ServiceTemplateDefinition) MaxValueDepth() int {
+	max := 0
+	update := func(depth int) {
+		if depth > max {
+			max = depth
+		}
+	}
+	assignments := func(props map[string]PropertyAssignment) {
+		for _, pa := range props {
+			update(assignmentDepth(pa))
+		}
+	}
+	for _, node := range s.TopologyTemplate.NodeTemplates {
+		assignments(node.Properties)
+		for _, c := range node.Capabilities {
+			update(valueDepth(c))
+		}
+		for _, iface := range node.Interfaces {
+			for _, op := range iface.Operations {
+				assignments(op.Inputs)
+			}
+		}
+	}
+	for _, rel := range s.TopologyTemplate.RelationshipTemplates {
+		assignments(rel.Properties)
+	}
+	for _, policies := range s.TopologyTemplate.Policies {
+		for _, policy := range policies {
+			assignments(policy.Properties)
+		}
+	}
+	for _, output := range s.TopologyTemplate.Outputs {
+		update(valueDepth(output.Value))
+	}
+	return max
+}
+
+// assignmentDepth returns the nesting depth of the value of the property assignment pa
+func assignmentDepth(pa PropertyAssignment) int {
+	if v, ok := pa["value"]; ok && len(v) == 1 {
+		return valueDepth(v[0])
+	}
+	return valueDepth(map[string][]interface{}(pa))
+}
+
+// valueDepth returns the nesting depth of the lists and maps of v
+func valueDepth(v interface{}) int {
+	var children []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		children = v
+	case map[interface{}]interface{}:
+		for _, c := range v {
+			children = append(children, c)
+		}
+	case map[string]interface{}:
+		for _, c := range v {
+			children = append(children, c)
+		}
+	case map[string][]interface{}:
+		for _, c := range v {
+			children = append(children, c)
+		}
+	default:
+		return 0
+	}
+	max := 0
+	for _, c := range children {
+		if d := valueDepth(c); d > max {
+			max = d
+		}
+	}
+	return max + 1
+}
+
+// sourceValueDepth returns the nesting depth of the values of the topology template of the document root,
+// before it is decoded: the depth of the YAML nodes found where MaxValueDepth looks for values.
+// It never exceeds the depth MaxValueDepth finds once the document is decoded, as the arguments
+// of a function are only wrapped in a list by the decoding.
+func sourceValueDepth(root *yaml3.Node) int {
+	max := 0
+	depths := make(map[*yaml3.Node]int)
+	update := func(n *yaml3.Node) {
+		if d := nodeDepth(n, depths); d > max {
+			max = d
+		}
+	}
+	each := func(n *yaml3.Node, fn func(*yaml3.Node)) {
+		for _, v := range mappingValues(n) {
+			fn(v)
+		}
+	}
+	topology := mappingValue(root, "topology_template")
+	each(mappingValue(topology, "node_templates"), func(node *yaml3.Node) {
+		each(mappingValue(node, "properties"), update)
+		each(mappingValue(node, "capabilities"), update)
+		each(mappingValue(node, "interfaces"), func(iface *yaml3.Node) {
+			each(iface, func(op *yaml3.Node) {
+				each(mappingValue(op, "inputs"), update)
+			})
+		})
+	})
+	each(mappingValue(topology, "relationship_templates"), func(rel *yaml3.Node) {
+		each(mappingValue(rel, "properties"), update)
+	})
+	if policies := resolveAlias(mappingValue(topology, "policies")); policies != nil && policies.Kind == yaml3.SequenceNode {
+		for _, policy := range policies.Content {
+			each(policy, func(p *yaml3.Node) {
+				each(mappingValue(p, "properties"), update)
+			})
+		}
+	}
+	each(mappingValue(topology, "outputs"), func(output *yaml3.Node) {
+		update(mappingValue(output, "value"))
+	})
+	return max
+}
+
+// nodeDepth returns the nesting depth of the sequences and mappings of n.
+// The depths are kept in depths so that a node reached through several aliases is walked once.
+func nodeDepth(n *yaml3.Node, depths map[*yaml3.Node]int) int {
+	n = resolveAlias(n)
+	if n == nil || (n.Kind != yaml3.SequenceNode && n.Kind != yaml3.MappingNode) {
+		return 0
+	}
+	if d, ok := depths[n]; ok {
+		return d
+	}
+	// An alias to one of its own ancestors must not loop
+	depths[n] = 0
+	max := 0
+	for i, c := range n.Content {
+		d := 0
+		switch {
+		case n.Kind == yaml3.SequenceNode:
+			d = nodeDepth(c, depths)
+		case i%2 == 0:
+			continue
+		case n.Content[i-1].Value == "<<":
+			// The entries of the merged mappings are the ones of n
+			d = nodeDepth(c, depths) - 1
+			if m := resolveAlias(c); m != nil && m.Kind == yaml3.SequenceNode {
+				d--
+			}
+		default:
+			d = nodeDepth(c, depths)
+		}
+		if d > max {
+			max = d
+		}
+	}
+	depths[n] = max + 1
+	return max + 1
+}
+
+// resolveAlias returns the node n refers to: the root of a document or the anchor of an alias
+func resolveAlias(n *yaml3.Node) *yaml3.Node {
+	for n != nil {
+		switch {
+		case n.Kind == yaml3.DocumentNode && len(n.Content) > 0:
+			n = n.Content[0]
+		case n.Kind == yaml3.AliasNode:
+			n = n.Alias
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value of the key of the mapping n, nil if it is not found
+func mappingValue(n *yaml3.Node, key string) *yaml3.Node {
+	n = resolveAlias(n)
+	if n == nil || n.Kind != yaml3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingValues returns the values of the mapping n
+func mappingValues(n *yaml3.Node) []*yaml3.Node {
+	n = resolveAlias(n)
+	if n == nil || n.Kind != yaml3.MappingNode {
+		return nil
+	}
+	var values []*yaml3.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		values = append(values, n.Content[i+1])
+	}
+	return values
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	yaml3 "gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+const nestedTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    app:
+      type: tosca.nodes.Root
+      capabilities:
+        feature:
+          properties:
+            config: %v
+`
+
+func TestMaxValueDepth(t *testing.T) {
+	s := parseString(t, strings.Replace(nestedTemplate, "%v", "[ [ 1, 2 ], [ 3 ] ]", 1))
+	// The value is nested in the map of the capability and the map of its properties
+	if depth := s.MaxValueDepth(); depth != 4 {
+		t.Fatalf("expected a depth of 4, got %v", depth)
+	}
+	var shallow ServiceTemplateDefinition
+	if err := shallow.ParseWithOptions(strings.NewReader(strings.Replace(nestedTemplate, "%v", "flat", 1)), ParseOptions{MaxValueDepth: 2}); err != nil {
+		t.Fatal(err)
+	}
+	deep := strings.Repeat("[ ", 50) + "x" + strings.Repeat(" ]", 50)
+	var s2 ServiceTemplateDefinition
+	err := s2.ParseWithOptions(strings.NewReader(strings.Replace(nestedTemplate, "%v", deep, 1)), ParseOptions{MaxValueDepth: 10})
+	if err == nil || !strings.Contains(err.Error(), "nested 52 levels deep, the limit is 10") {
+		t.Fatalf("expected a nesting error, got %v", err)
+	}
+	if err := s2.Parse(strings.NewReader(strings.Replace(nestedTemplate, "%v", deep, 1))); err != nil {
+		t.Fatalf("the depth should not be limited by default, got %v", err)
+	}
+}
+
+func TestMaxValueDepthBeforeDecoding(t *testing.T) {
+	deep := strings.Repeat("[ ", 50) + "x" + strings.Repeat(" ]", 50)
+	// The node types cannot be decoded: the depth must be rejected first
+	doc := strings.Replace(nestedTemplate, "%v", deep, 1) + "node_types: 5\n"
+	var s ServiceTemplateDefinition
+	err := s.ParseWithOptions(strings.NewReader(doc), ParseOptions{MaxValueDepth: 10})
+	if err == nil || !strings.Contains(err.Error(), "nested 52 levels deep, the limit is 10") {
+		t.Fatalf("expected a nesting error before decoding, got %v", err)
+	}
+	// The entries merged in a mapping are not nested in the merge key
+	merged := `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    app:
+      type: tosca.nodes.Root
+      properties:
+        base: &base { a: 1 }
+        config: { <<: *base, b: 2 }
+`
+	var src yaml3.Node
+	if err := yaml3.Unmarshal([]byte(merged), &src); err != nil {
+		t.Fatal(err)
+	}
+	if depth := sourceValueDepth(&src); depth != 1 {
+		t.Fatalf("expected a depth of 1, got %v", depth)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+)
+
+// Descriptions returns the non-empty descriptions of the template, of its type definitions
+// and of the properties, attributes, capabilities, requirements and operations they define.
+// The descriptions are keyed by their path in the document, the elements of the path being
+// separated by a slash, such as "node_types/tosca.nodes.Compute/capabilities/host".
+func (s *ServiceTemplateDefinition) Descriptions() map[string]string {
+	d := make(descriptions)
+	d.add(s.Description, "description")
+	for name, t := range s.DataTypes {
+		d.add(t.Description, "data_types", name)
+		d.properties(t.Properties, "data_types", name, "properties")
+	}
+	for name, t := range s.CapabilityTypes {
+		d.add(t.Description, "capability_types", name)
+		d.properties(t.Properties, "capability_types", name, "properties")
+		d.attributes(t.Attributes, "capability_types", name)
+	}
+	for name, t := range s.InterfaceTypes {
+		d.add(t.Description, "interface_types", name)
+		d.properties(t.Inputs, "interface_types", name, "inputs")
+		for op, def := range t.Operations {
+			d.add(def.Description, "interface_types", name, op)
+		}
+	}
+	for name, t := range s.RelationshipTypes {
+		d.add(t.Description, "relationship_types", name)
+		d.properties(t.Properties, "relationship_types", name, "properties")
+		d.attributes(t.Attributes, "relationship_types", name)
+		d.interfaces(t.Interfaces, "relationship_types", name)
+	}
+	for name, t := range s.NodeTypes {
+		d.add(t.Description, "node_types", name)
+		d.properties(t.Properties, "node_types", name, "properties")
+		d.attributes(t.Attributes, "node_types", name)
+		d.interfaces(t.Interfaces, "node_types", name)
+		for capName, c := range t.Capabilities {
+			d.add(c.Description, "node_types", name, "capabilities", capName)
+		}
+		for _, req := range t.Requirements {
+			for reqName, r := range req {
+				d.add(r.Description, "node_types", name, "requirements", reqName)
+			}
+		}
+	}
+	d.properties(s.TopologyTemplate.Inputs, "topology_template", "inputs")
+	for name, n := range s.TopologyTemplate.NodeTemplates {
+		d.add(n.Decription, "topology_template", "node_templates", name)
+	}
+	for name, r := range s.TopologyTemplate.RelationshipTemplates {
+		d.add(r.Description, "topology_template", "relationship_templates", name)
+		d.interfaces(r.Interfaces, "topology_template", "relationship_templates", name)
+	}
+	for name, o := range s.TopologyTemplate.Outputs {
+		d.add(o.Description, "topology_template", "outputs", name)
+	}
+	return d
+}
+
+// descriptions maps the path of an element to its description
+type descriptions map[string]string
+
+func (d descriptions) add(description string, path ...string) {
+	if description != "" {
+		d[strings.Join(path, "/")] = description
+	}
+}
+
+// properties adds the descriptions of the property definitions of the section path
+func (d descriptions) properties(props map[string]PropertyDefinition, path ...string) {
+	for name, p := range props {
+		d.add(p.Description, append(path, name)...)
+	}
+}
+
+func (d descriptions) attributes(attrs map[string]AttributeDefinition, path ...string) {
+	for name, a := range attrs {
+		d.add(a.Description, append(path, "attributes", name)...)
+	}
+}
+
+func (d descriptions) interfaces(intfs map[string]InterfaceDefinition, path ...string) {
+	for name, intf := range intfs {
+		for op, def := range intf.Operations {
+			d.add(def.Description, append(path, "interfaces", name, op)...)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"testing"
+)
+
+const descriptionsTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+description: A documented template
+node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.Root
+    description: An application
+    properties:
+      port:
+        type: integer
+        description: The listening port
+    capabilities:
+      api:
+        type: tosca.capabilities.Endpoint
+        description: The public API
+    requirements:
+      - database:
+          capability: tosca.capabilities.Endpoint.Database
+          description: The database of the application
+    interfaces:
+      Standard:
+        create:
+          implementation: create.sh
+          description: Installs the application
+topology_template:
+  inputs:
+    port:
+      type: integer
+      description: The port to listen to
+  node_templates:
+    app:
+      type: my.nodes.App
+      description: The application instance
+  outputs:
+    url:
+      description: The URL of the application
+      value: { get_attribute: [ app, url ] }
+`
+
+func TestDescriptions(t *testing.T) {
+	d := parseString(t, descriptionsTemplate).Descriptions()
+	expected := map[string]string{
+		"description":                                        "A documented template",
+		"node_types/my.nodes.App":                            "An application",
+		"node_types/my.nodes.App/properties/port":            "The listening port",
+		"node_types/my.nodes.App/capabilities/api":           "The public API",
+		"node_types/my.nodes.App/requirements/database":      "The database of the application",
+		"node_types/my.nodes.App/interfaces/Standard/create": "Installs the application",
+		"topology_template/inputs/port":                      "The port to listen to",
+		"topology_template/node_templates/app":               "The application instance",
+		"topology_template/outputs/url":                      "The URL of the application",
+		"node_types/tosca.nodes.Root":                        "The TOSCA Node Type all other TOSCA base Node Types derive from",
+	}
+	for path, desc := range expected {
+		if d[path] != desc {
+			t.Errorf("%v: expected %q, got %q", path, desc, d[path])
+		}
+	}
+}
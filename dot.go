@@ -0,0 +1,68 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ExportDOT renders the topology in the Graphviz DOT format: the node templates are the nodes of
+// the graph and their requirements are directed edges to their targets, labeled with the
+// short name of the relationship type (such as HostedOn, DependsOn or ConnectsTo).
+func (s *ServiceTemplateDefinition) ExportDOT() string {
+	var b bytes.Buffer
+	b.WriteString("digraph G {\n")
+	names := s.nodeNames()
+	for _, name := range names {
+		fmt.Fprintf(&b, "\t%q [label=\"%v\\n%v\"];\n", name, name, s.TopologyTemplate.NodeTemplates[name].Type)
+	}
+	for _, name := range names {
+		for _, e := range s.dotEdges(name) {
+			fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", name, e[0], e[1])
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotEdges returns the targets of the requirements of the node template name with their labels.
+// The requirements that cannot be matched are drawn with the relationship given in the template, if any.
+func (s *ServiceTemplateDefinition) dotEdges(name string) [][2]string {
+	var edges [][2]string
+	label := func(relationship, requirement string) string {
+		if relationship == "" {
+			return requirement
+		}
+		return relationship[strings.LastIndex(relationship, ".")+1:]
+	}
+	matches, err := s.MatchRequirements(name)
+	if err == nil {
+		for _, m := range matches {
+			edges = append(edges, [2]string{m.Target, label(m.Relationship, m.Requirement)})
+		}
+		return edges
+	}
+	for _, req := range s.TopologyTemplate.NodeTemplates[name].Requirements {
+		for r, ra := range req {
+			if _, ok := s.TopologyTemplate.NodeTemplates[ra.Node]; ok {
+				edges = append(edges, [2]string{ra.Node, label(ra.RelationshipName, r)})
+			}
+		}
+	}
+	return edges
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportDOT(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    db:
+      type: tosca.nodes.DBMS
+      requirements:
+        - host: server
+    web:
+      type: tosca.nodes.WebServer
+      requirements:
+        - host: server
+        - dependency: db
+`)
+	dot := s.ExportDOT()
+	for _, expected := range []string{
+		"digraph G {\n",
+		"\t\"server\" [label=\"server\\ntosca.nodes.Compute\"];\n",
+		"\t\"db\" -> \"server\" [label=\"HostedOn\"];\n",
+		"\t\"web\" -> \"server\" [label=\"HostedOn\"];\n",
+		"\t\"web\" -> \"db\" [label=\"DependsOn\"];\n",
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("the DOT output should contain %q:\n%v", expected, dot)
+		}
+	}
+	if strings.Contains(dot, "\"server\" ->") {
+		t.Errorf("server has no requirement:\n%v", dot)
+	}
+}
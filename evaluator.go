@@ -0,0 +1,74 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+)
+
+// FunctionPolicy tells how the evaluation handles the functions it does not implement, such as vendor extensions
+type FunctionPolicy int
+
+const (
+	// PassThroughFunctions leaves the unknown functions intact: their evaluation returns the function call itself
+	PassThroughFunctions FunctionPolicy = iota
+	// StrictFunctions makes the evaluation of an unknown function fail
+	StrictFunctions
+)
+
+// EvaluateOptions configures the evaluation of the functions
+type EvaluateOptions struct {
+	UnknownFunctions FunctionPolicy // The handling of the functions that are not implemented
+}
+
+// Context holds the runtime values against which the functions of a template are evaluated
+type Context struct {
+	Inputs  map[string]interface{} // The values of the inputs of the topology, by name
+	Options EvaluateOptions        // The options of the evaluation
+}
+
+// input returns the value of the input name: the runtime value if any, otherwise the value
+// assigned in the template, otherwise its default value.
+func (c *Context) input(s *ServiceTemplateDefinition, name string) interface{} {
+	if v, ok := c.Inputs[name]; ok {
+		return v
+	}
+	def := s.TopologyTemplate.Inputs[name]
+	if def.Value != "" {
+		return def.Value
+	}
+	return def.Default
+}
+
+// Evaluator returns a function evaluating the property located at path against a runtime context.
+// The path is the name of a node template followed by the name of one of its properties.
+// The template is not modified, so the returned function may be called again whenever the context changes.
+func (s *ServiceTemplateDefinition) Evaluator(path []string) (func(runtime Context) (interface{}, error), error) {
+	if len(path) != 2 {
+		return nil, fmt.Errorf("Invalid property path %v: expected a node and a property", path)
+	}
+	node, ok := s.TopologyTemplate.NodeTemplates[path[0]]
+	if !ok {
+		return nil, fmt.Errorf("Node %v not found", path[0])
+	}
+	pa, ok := node.Properties[path[1]]
+	if !ok {
+		return nil, fmt.Errorf("Node %v: property %v not found", path[0], path[1])
+	}
+	return func(runtime Context) (interface{}, error) {
+		return s.evaluate(PA{PA: pa, Origin: path[0]}, &runtime)
+	}, nil
+}
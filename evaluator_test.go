@@ -0,0 +1,116 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"testing"
+)
+
+const evaluatorTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    port:
+      type: integer
+      default: 8080
+  node_templates:
+    web:
+      type: tosca.nodes.WebServer
+      properties:
+        component_version: { get_input: port }
+    app:
+      type: tosca.nodes.WebApplication
+      properties:
+        context_root: { concat: [ "/app-", { get_property: [ web, component_version ] } ] }
+`
+
+func TestEvaluator(t *testing.T) {
+	s := parseString(t, evaluatorTemplate)
+	eval, err := s.Evaluator([]string{"web", "component_version"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, port := range []string{"80", "443"} {
+		v, err := eval(Context{Inputs: map[string]interface{}{"port": port}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != port {
+			t.Fatalf("expected %v, got %v", port, v)
+		}
+	}
+	v, err := eval(Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "8080" {
+		t.Fatalf("expected the default value 8080, got %v", v)
+	}
+}
+
+func TestEvaluatorNested(t *testing.T) {
+	s := parseString(t, evaluatorTemplate)
+	eval, err := s.Evaluator([]string{"app", "context_root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := eval(Context{Inputs: map[string]interface{}{"port": "9000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "/app-9000" {
+		t.Fatalf("expected /app-9000, got %v", v)
+	}
+}
+
+func TestEvaluatorInvalidPath(t *testing.T) {
+	s := parseString(t, evaluatorTemplate)
+	for _, path := range [][]string{{"web"}, {"db", "port"}, {"web", "missing"}} {
+		if _, err := s.Evaluator(path); err == nil {
+			t.Errorf("%v should not be a valid path", path)
+		}
+	}
+}
+
+func TestEvaluateUnknownFunction(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    web:
+      type: tosca.nodes.WebServer
+      properties:
+        component_version: { get_vendor_thing: [ SELF, version ] }
+`)
+	pa := s.GetProperty("web", "component_version")
+	v, err := s.EvaluateStatementWithOptions(pa, EvaluateOptions{UnknownFunctions: PassThroughFunctions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, ok := v.(PropertyAssignment)
+	if !ok || len(call["get_vendor_thing"]) != 2 {
+		t.Fatalf("the unknown function should be left intact, got %v", v)
+	}
+	_, err = s.EvaluateStatementWithOptions(pa, EvaluateOptions{UnknownFunctions: StrictFunctions})
+	if err == nil || err.Error() != "Unknown function get_vendor_thing" {
+		t.Fatalf("expected an unknown function error, got %v", err)
+	}
+	eval, err := s.Evaluator([]string{"web", "component_version"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eval(Context{Options: EvaluateOptions{UnknownFunctions: StrictFunctions}}); err == nil {
+		t.Fatal("the strict policy should apply to the evaluators")
+	}
+}
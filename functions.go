@@ -0,0 +1,205 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// operand is a numeric argument of an arithmetic function.
+// The value of a scalar-unit is expressed in its base unit
+type operand struct {
+	value   float64
+	unit    string // The base unit of the operand, empty for a plain number
+	integer bool   // True if the operand is an integer
+}
+
+// toOperand converts v, a number or a scalar-unit, into an operand
+func toOperand(v interface{}) (operand, error) {
+	switch val := v.(type) {
+	case int:
+		return operand{float64(val), "", true}, nil
+	case int64:
+		return operand{float64(val), "", true}, nil
+	case float64:
+		return operand{val, "", false}, nil
+	case Scalar:
+		return toOperand(string(val))
+	case string:
+		sv, err := Scalar(val).Evaluate()
+		switch sv := sv.(type) {
+		case Size:
+			return operand{float64(sv), "B", false}, nil
+		case Frequency:
+			return operand{float64(sv), "Hz", false}, nil
+		case time.Duration:
+			return operand{float64(sv), "ns", false}, nil
+		}
+		if err != ErrMissingUnit {
+			return operand{}, fmt.Errorf("%q is neither a number nor a scalar-unit", val)
+		}
+		str := strings.TrimSpace(val)
+		if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return operand{float64(i), "", true}, nil
+		}
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("%q is not a number", val)
+		}
+		return operand{f, "", false}, nil
+	}
+	return operand{}, fmt.Errorf("%v is neither a number nor a scalar-unit", v)
+}
+
+// result returns the result val of the function fn as an int or a float64 if unit is empty,
+// or as a Scalar expressed in unit. A scalar cannot be negative and an int must hold val.
+func result(fn string, val float64, unit string, integer bool) (interface{}, error) {
+	switch {
+	case math.IsNaN(val) || math.IsInf(val, 0):
+		return nil, fmt.Errorf("%v: the result is not a number", fn)
+	case unit != "":
+		if val < 0 {
+			return nil, fmt.Errorf("%v: the result %v %v is a negative scalar", fn, val, unit)
+		}
+		return Scalar(strconv.FormatFloat(val, 'f', -1, 64) + " " + unit), nil
+	case integer:
+		if val < math.MinInt64 || val >= math.MaxInt64 || int64(int(val)) != int64(val) {
+			return nil, fmt.Errorf("%v: the result %v is out of the range of an integer", fn, val)
+		}
+		return int(val), nil
+	default:
+		return val, nil
+	}
+}
+
+// evaluateArgument returns the value of the argument arg of a function, evaluating it if it is a function itself
+func (s *ServiceTemplateDefinition) evaluateArgument(arg interface{}, origin string, ctx *Context) (interface{}, error) {
+	m, ok := arg.(map[interface{}]interface{})
+	if !ok {
+		return arg, nil
+	}
+	pa := make(PropertyAssignment, len(m))
+	for k, v := range m {
+		args, ok := v.([]interface{})
+		if !ok {
+			args = []interface{}{v}
+		}
+		if len(args) > 0 && args[0] == "SELF" {
+			args = append([]interface{}{origin}, args[1:]...)
+		}
+		pa[fmt.Sprint(k)] = args
+	}
+	return s.evaluate(PA{PA: pa, Origin: origin}, ctx)
+}
+
+// arithmetic evaluates the arithmetic function fn (add, subtract, multiply or divide) on its two arguments.
+// The operands may be numbers or scalar-units; the result of an operation on scalar-units
+// is a Scalar expressed in the base unit of the dimension, the ratio of two scalar-units of the same
+// dimension is a float64.
+func (s *ServiceTemplateDefinition) arithmetic(fn string, args []interface{}, origin string, ctx *Context) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%v expects 2 arguments, got %v", fn, len(args))
+	}
+	var ops [2]operand
+	for i, arg := range args {
+		v, err := s.evaluateArgument(arg, origin, ctx)
+		if err != nil {
+			return nil, err
+		}
+		ops[i], err = toOperand(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", fn, err)
+		}
+	}
+	a, b := ops[0], ops[1]
+	integer := a.integer && b.integer
+	switch strings.TrimPrefix(fn, "$") {
+	case "add":
+		if a.unit != b.unit {
+			return nil, fmt.Errorf("%v: cannot add %v and %v", fn, args[0], args[1])
+		}
+		return result(fn, a.value+b.value, a.unit, integer)
+	case "subtract":
+		if a.unit != b.unit {
+			return nil, fmt.Errorf("%v: cannot subtract %v from %v", fn, args[1], args[0])
+		}
+		return result(fn, a.value-b.value, a.unit, integer)
+	case "multiply":
+		if a.unit != "" && b.unit != "" {
+			return nil, fmt.Errorf("%v: cannot multiply two scalar-units %v and %v", fn, args[0], args[1])
+		}
+		return result(fn, a.value*b.value, a.unit+b.unit, integer)
+	case "divide":
+		if b.value == 0 {
+			return nil, fmt.Errorf("%v: division by zero", fn)
+		}
+		switch {
+		case a.unit == b.unit:
+			return a.value / b.value, nil
+		case b.unit == "":
+			return result(fn, a.value/b.value, a.unit, false)
+		default:
+			return nil, fmt.Errorf("%v: cannot divide %v by %v", fn, args[0], args[1])
+		}
+	}
+	return nil, fmt.Errorf("Unknown function %v", fn)
+}
+
+// getArtifact evaluates the function get_artifact: [ node, artifact_name, location, remove ].
+// It returns the path of the artifact file, or location if it is a path where the artifact is to be made available.
+// If location is LOCAL_FILE, the content of the artifact file is returned embedded in a data URI.
+func (s *ServiceTemplateDefinition) getArtifact(args []interface{}, origin string) (interface{}, error) {
+	if len(args) < 2 || len(args) > 4 {
+		return nil, fmt.Errorf("get_artifact expects 2 to 4 arguments, got %v", len(args))
+	}
+	name := fmt.Sprint(args[0])
+	if name == "SELF" {
+		name = origin
+	}
+	node, ok := s.TopologyTemplate.NodeTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("get_artifact: node %v not found", name)
+	}
+	artifact, ok := node.Artifcats[fmt.Sprint(args[1])]
+	if !ok {
+		return nil, fmt.Errorf("get_artifact: node %v has no artifact %v", name, args[1])
+	}
+	file := artifact.File()
+	if len(args) < 3 {
+		return file, nil
+	}
+	location := fmt.Sprint(args[2])
+	if location != "LOCAL_FILE" {
+		return location, nil
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("get_artifact: %v", err)
+	}
+	mediaType := mime.TypeByExtension(filepath.Ext(file))
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(content), nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+const arithmeticTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    replicas:
+      type: integer
+      default: 3
+  node_templates:
+    storage:
+      type: tosca.nodes.BlockStorage
+      properties:
+        size: { $multiply: [ 2, 1 GiB ] }
+        total: { multiply: [ { get_input: replicas }, 1 GiB ] }
+`
+
+func TestArithmeticAdd(t *testing.T) {
+	var s ServiceTemplateDefinition
+	v, err := s.EvaluateStatement(PA{PA: PropertyAssignment{"$add": {1, 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 3 {
+		t.Fatalf("expected 3, got %v (%T)", v, v)
+	}
+	v, err = s.EvaluateStatement(PA{PA: PropertyAssignment{"subtract": {"2 GiB", "512 MiB"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != Scalar("1610612736 B") {
+		t.Fatalf("expected 1610612736 B, got %v", v)
+	}
+	if _, err := s.EvaluateStatement(PA{PA: PropertyAssignment{"add": {"1 GiB", "1 GHz"}}}); err == nil {
+		t.Fatal("adding a size and a frequency should fail")
+	}
+	if _, err := s.EvaluateStatement(PA{PA: PropertyAssignment{"subtract": {"512 MiB", "2 GiB"}}}); err == nil || !strings.Contains(err.Error(), "negative scalar") {
+		t.Fatalf("a negative size should fail, got %v", err)
+	}
+	if _, err := s.EvaluateStatement(PA{PA: PropertyAssignment{"add": {9000000000000000000, 9000000000000000000}}}); err == nil || !strings.Contains(err.Error(), "out of the range of an integer") {
+		t.Fatalf("an integer overflow should fail, got %v", err)
+	}
+	v, err = s.EvaluateStatement(PA{PA: PropertyAssignment{"subtract": {1, 3}}})
+	if err != nil || v != -2 {
+		t.Fatalf("a negative integer is valid, expected -2, got %v (%v)", v, err)
+	}
+}
+
+func TestArithmeticMultiply(t *testing.T) {
+	s := parseString(t, arithmeticTemplate)
+	s.TopologyTemplate.Inputs["replicas"] = PropertyDefinition{Value: "3"}
+	v, err := s.EvaluateStatement(s.GetProperty("storage", "size"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != Scalar("2147483648 B") {
+		t.Fatalf("expected 2147483648 B, got %v", v)
+	}
+	v, err = s.EvaluateStatement(s.GetProperty("storage", "total"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != Scalar("3221225472 B") {
+		t.Fatalf("expected 3221225472 B, got %v", v)
+	}
+	if _, err := s.EvaluateStatement(PA{PA: PropertyAssignment{"multiply": {"1 GiB", "1 GiB"}}}); err == nil {
+		t.Fatal("multiplying two sizes should fail")
+	}
+}
+
+func TestArithmeticDivide(t *testing.T) {
+	var s ServiceTemplateDefinition
+	v, err := s.EvaluateStatement(PA{PA: PropertyAssignment{"$divide": {"512 MiB", "1 GiB"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 0.5 {
+		t.Fatalf("expected 0.5, got %v (%T)", v, v)
+	}
+	v, err = s.EvaluateStatement(PA{PA: PropertyAssignment{"divide": {"1 GiB", 4}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != Scalar("268435456 B") {
+		t.Fatalf("expected 268435456 B, got %v", v)
+	}
+	if _, err := s.EvaluateStatement(PA{PA: PropertyAssignment{"divide": {"1 GiB", "0 B"}}}); err == nil {
+		t.Fatal("a division by zero should fail")
+	}
+}
+
+const artifactTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    app:
+      type: tosca.nodes.SoftwareComponent
+      artifacts:
+        config:
+          file: CONFIG_FILE
+          type: tosca.artifacts.File
+      properties:
+        component_version: { get_artifact: [ SELF, config ] }
+`
+
+func TestGetArtifact(t *testing.T) {
+	f, err := ioutil.TempFile("", "config*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"port":80}`)
+	f.Close()
+	s := parseString(t, strings.Replace(artifactTemplate, "CONFIG_FILE", f.Name(), 1))
+	v, err := s.EvaluateStatement(s.GetProperty("app", "component_version"))
+	if err != nil || v != f.Name() {
+		t.Fatalf("expected the artifact path %v, got %v (%v)", f.Name(), v, err)
+	}
+	v, err = s.EvaluateStatement(PA{PA: PropertyAssignment{"get_artifact": {"app", "config", "/opt/app/config.json"}}})
+	if err != nil || v != "/opt/app/config.json" {
+		t.Fatalf("expected the deployment location, got %v (%v)", v, err)
+	}
+	v, err = s.EvaluateStatement(PA{PA: PropertyAssignment{"get_artifact": {"app", "config", "LOCAL_FILE"}}})
+	if err != nil || v != "data:application/json;base64,eyJwb3J0Ijo4MH0=" {
+		t.Fatalf("expected a data URI, got %v (%v)", v, err)
+	}
+	_, err = s.EvaluateStatement(PA{PA: PropertyAssignment{"get_artifact": {"app", "missing"}}})
+	if err == nil || !strings.Contains(err.Error(), "no artifact missing") {
+		t.Fatalf("expected an unknown artifact error, got %v", err)
+	}
+}
@@ -15,6 +15,11 @@ limitations under the License.
 */
 package toscalib
 
+import (
+	"fmt"
+	"sort"
+)
+
 // Input corresponds to  `yaml:"inputs,omitempty" json:"inputs,omitempty"`
 type Input struct {
 	Value            string      `json:"value"`
@@ -53,3 +58,89 @@ func (i *Input) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 
 }
+
+// MissingRequiredInputs returns the sorted names of the required inputs of the
+// topology that have no default value and are not present in provided
+func (s *ServiceTemplateDefinition) MissingRequiredInputs(provided map[string]interface{}) []string {
+	var missing []string
+	for name, input := range s.TopologyTemplate.Inputs {
+		if !input.Required || input.HasDefault() {
+			continue
+		}
+		if _, ok := provided[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// InputConstraints returns the constraint clauses applying to the input name of the topology:
+// the constraints of its definition followed by the ones of its data type, from the most derived type
+// to its ancestors. A form may enforce them before the template is deployed.
+func (s *ServiceTemplateDefinition) InputConstraints(name string) ([]ConstraintClause, error) {
+	def, ok := s.TopologyTemplate.Inputs[name]
+	if !ok {
+		return nil, fmt.Errorf("Input %v not found", name)
+	}
+	constraints := append([]ConstraintClause{}, def.Constraints...)
+	visited := make(map[string]bool)
+	for n := def.Type; n != "" && !visited[n]; {
+		visited[n] = true
+		dt, ok := s.DataTypes[n]
+		if !ok {
+			break
+		}
+		constraints = append(constraints, dt.Constraints...)
+		n = dt.DerivedFrom
+	}
+	return constraints, nil
+}
+
+// InputSpec describes an input of the topology, as returned by InputSpecs
+type InputSpec struct {
+	Name        string             // The name of the input
+	Type        string             // The data type of the input
+	Description string             // The description of the input
+	Default     string             // The default value of the input, empty if it has none
+	Required    bool               // Whether a value must be given to the input
+	Constraints []ConstraintClause // The constraints of the input merged with the ones of its data type, see InputConstraints
+}
+
+// InputSpecs returns the description of the inputs of the topology in the order of their declaration
+// in the source; the inputs that are not located in the source, such as the ones defined in code, are sorted by name.
+// A tool may use it to prompt for the inputs before deploying the template.
+func (s *ServiceTemplateDefinition) InputSpecs() []InputSpec {
+	names := make([]string, 0, len(s.TopologyTemplate.Inputs))
+	lines := make(map[string]int, len(s.TopologyTemplate.Inputs))
+	for name := range s.TopologyTemplate.Inputs {
+		names = append(names, name)
+		lines[name] = s.exactLine("topology_template", "inputs", name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		li, lj := lines[names[i]], lines[names[j]]
+		if (li == 0) != (lj == 0) {
+			// The unlocated inputs come after the located ones
+			return lj == 0
+		}
+		if li != lj {
+			return li < lj
+		}
+		return names[i] < names[j]
+	})
+	specs := make([]InputSpec, len(names))
+	for i, name := range names {
+		def := s.TopologyTemplate.Inputs[name]
+		// The input exists, so InputConstraints cannot fail
+		constraints, _ := s.InputConstraints(name)
+		specs[i] = InputSpec{
+			Name:        name,
+			Type:        def.Type,
+			Description: def.Description,
+			Default:     def.Default,
+			Required:    def.Required,
+			Constraints: constraints,
+		}
+	}
+	return specs
+}
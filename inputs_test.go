@@ -0,0 +1,165 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"testing"
+)
+
+const inputsTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    cpus:
+      type: integer
+    mem_size:
+      type: scalar-unit.size
+    db_name:
+      type: string
+    db_user:
+      type: string
+      default: admin
+    db_port:
+      type: integer
+      default: 3306
+    comment:
+      type: string
+      required: false
+    suffix:
+      type: string
+      default: ""
+  node_templates:
+`
+
+func TestMissingRequiredInputs(t *testing.T) {
+	s := parseString(t, inputsTemplate)
+	missing := s.MissingRequiredInputs(map[string]interface{}{
+		"cpus":    2,
+		"db_port": 5432,
+	})
+	expected := []string{"db_name", "mem_size"}
+	if !reflect.DeepEqual(missing, expected) {
+		t.Fatalf("expected %v, got %v", expected, missing)
+	}
+	missing = s.MissingRequiredInputs(map[string]interface{}{
+		"cpus":     2,
+		"mem_size": "4 GB",
+		"db_name":  "wordpress",
+	})
+	if len(missing) != 0 {
+		t.Fatalf("no input should be missing, got %v", missing)
+	}
+	if s.TopologyTemplate.Inputs["cpus"].Value != "" {
+		t.Fatal("the inputs should not be modified")
+	}
+}
+
+func TestPropertyDefinitionHasDefault(t *testing.T) {
+	s := parseString(t, inputsTemplate)
+	for name, expected := range map[string]bool{"db_user": true, "suffix": true, "cpus": false} {
+		if has := s.TopologyTemplate.Inputs[name].HasDefault(); has != expected {
+			t.Errorf("input %v: expected HasDefault %v, got %v", name, expected, has)
+		}
+	}
+	if !s.TopologyTemplate.Inputs["cpus"].Required {
+		t.Error("an input that omits required should be required")
+	}
+	if s.TopologyTemplate.Inputs["comment"].Required {
+		t.Error("an input declared with required: false should not be required")
+	}
+	if !(PropertyDefinition{Default: "admin"}).HasDefault() {
+		t.Error("a definition built with a default should have a default")
+	}
+}
+
+func TestInputConstraints(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+data_types:
+  my.datatypes.Port:
+    derived_from: integer
+    constraints:
+      - greater_than: 0
+topology_template:
+  inputs:
+    port:
+      type: my.datatypes.Port
+      constraints:
+        - in_range: [ 1024, 65535 ]
+        - valid_values: [ 8080, 8443 ]
+`)
+	constraints, err := s.InputConstraints("port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ConstraintClause{
+		{"in_range", []interface{}{1024, 65535}},
+		{"valid_values", []interface{}{8080, 8443}},
+		{"greater_than", 0},
+	}
+	if !reflect.DeepEqual(constraints, expected) {
+		t.Fatalf("expected the constraints %v, got %v", expected, constraints)
+	}
+	if _, err := s.InputConstraints("host"); err == nil {
+		t.Fatal("an undeclared input should have no constraints")
+	}
+}
+
+func TestInputSpecs(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    zone:
+      type: string
+      description: The availability zone
+      required: false
+    port:
+      type: integer
+      default: 8080
+      constraints:
+        - in_range: [ 1024, 65535 ]
+    admin:
+      type: string
+`)
+	expected := []InputSpec{
+		{Name: "zone", Type: "string", Description: "The availability zone", Constraints: []ConstraintClause{}},
+		{Name: "port", Type: "integer", Default: "8080", Required: true, Constraints: []ConstraintClause{{"in_range", []interface{}{1024, 65535}}}},
+		{Name: "admin", Type: "string", Required: true, Constraints: []ConstraintClause{}},
+	}
+	if specs := s.InputSpecs(); !reflect.DeepEqual(specs, expected) {
+		t.Fatalf("expected the input specs %+v, got %+v", expected, specs)
+	}
+}
+
+func TestInputSpecsUnlocated(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    zone:
+      type: string
+    port:
+      type: integer
+`)
+	s.TopologyTemplate.Inputs["region"] = PropertyDefinition{Type: "string"}
+	s.TopologyTemplate.Inputs["admin"] = PropertyDefinition{Type: "string"}
+	var names []string
+	for _, spec := range s.InputSpecs() {
+		names = append(names, spec.Name)
+	}
+	expected := []string{"zone", "port", "admin", "region"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected the inputs in the order %v, got %v", expected, names)
+	}
+}
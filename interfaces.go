@@ -15,15 +15,68 @@ limitations under the License.
 */
 package toscalib
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // InterfaceType as described in Appendix A 6.4
 // An Interface Type is a reusable entity that describes a set of operations that can be used to interact with or manage a node or relationship in a TOSCA topology.
 type InterfaceType struct {
+	DerivedFrom string                         `yaml:"derived_from,omitempty"` // An optional parent Interface Type name this new Interface Type derives from
 	Description string                         `yaml:"description,omitempty"`
 	Version     Version                        `yaml:"version,omitempty"`
 	Operations  map[string]OperationDefinition `yaml:"operations,inline"`
 	Inputs      map[string]PropertyDefinition  `yaml:"inputs,omitempty" json:"inputs"` // The optional list of input parameter definitions.
 }
 
+// rootInterfaceType is the interface type all the interface types derive from.
+// It is not part of the normative types and is accepted without definition.
+const rootInterfaceType = "tosca.interfaces.Root"
+
+// InterfaceType returns the interface type named name with the operations and inputs inherited from its ancestors
+func (s *ServiceTemplateDefinition) InterfaceType(name string) (InterfaceType, error) {
+	var chain []InterfaceType
+	visited := make(map[string]bool)
+	for n := name; n != ""; {
+		if visited[n] {
+			return InterfaceType{}, fmt.Errorf("Interface type %v: cyclic derivation through %v", name, n)
+		}
+		visited[n] = true
+		it, ok := s.InterfaceTypes[n]
+		if !ok {
+			if n == rootInterfaceType {
+				break
+			}
+			return InterfaceType{}, fmt.Errorf("Interface type %v not found", n)
+		}
+		chain = append(chain, it)
+		n = it.DerivedFrom
+	}
+	flat := InterfaceType{
+		Operations: make(map[string]OperationDefinition),
+		Inputs:     make(map[string]PropertyDefinition),
+	}
+	if len(chain) == 0 {
+		return flat, nil
+	}
+	flat.DerivedFrom = chain[0].DerivedFrom
+	flat.Version = chain[0].Version
+	for i := len(chain) - 1; i >= 0; i-- {
+		it := chain[i]
+		if it.Description != "" {
+			flat.Description = it.Description
+		}
+		for k, v := range it.Operations {
+			flat.Operations[k] = v
+		}
+		for k, v := range it.Inputs {
+			flat.Inputs[k] = v
+		}
+	}
+	return flat, nil
+}
+
 // InterfaceDefinition is related to a node type
 //type InterfaceDefinitionTemplate map[string]OperationDefinition
 
@@ -31,20 +84,20 @@ type InterfaceType struct {
 type OperationDefinition struct {
 	Inputs         map[string]PropertyAssignment `yaml:"inputs,omitempty"`
 	Description    string                        `yaml:"description,omitempty"`
-	Implementation string                        `yaml:"implementation,omitempty"`
+	Implementation OperationImplementation       `yaml:"implementation,omitempty"`
 }
 
 func (i *OperationDefinition) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var s string
 	if err := unmarshal(&s); err == nil {
-		i.Implementation = s
+		i.Implementation = OperationImplementation{Primary: s}
 		return nil
 	}
 	var str struct {
 		Inputs map[string]PropertyAssignment `yaml:"inputs,omitempty"`
 		//Implementation      string                 `yaml:"implementation,omitempty"`
-		Description    string `yaml:"description,omitempty"`
-		Implementation string `yaml:"implementation,omitempty"`
+		Description    string                  `yaml:"description,omitempty"`
+		Implementation OperationImplementation `yaml:"implementation,omitempty"`
 	}
 	if err := unmarshal(&str); err != nil {
 		return err
@@ -55,29 +108,136 @@ func (i *OperationDefinition) UnmarshalYAML(unmarshal func(interface{}) error) e
 	return nil
 }
 
+// OperationImplementation is the implementation of an operation.
+// It is either the name of a single artifact (a script path) or the primary artifact
+// with the list of the artifacts it depends on.
+type OperationImplementation struct {
+	Primary      string   `yaml:"primary" json:"primary"`                               // The primary artifact implementing the operation.
+	Dependencies []string `yaml:"dependencies,omitempty" json:"dependencies,omitempty"` // The optional list of artifacts the primary artifact depends on.
+}
+
+// UnmarshalYAML accepts both the short notation (the primary artifact) and the map notation
+func (o *OperationImplementation) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		o.Primary = s
+		o.Dependencies = nil
+		return nil
+	}
+	var str struct {
+		Primary      string   `yaml:"primary"`
+		Dependencies []string `yaml:"dependencies,omitempty"`
+	}
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	o.Primary = str.Primary
+	o.Dependencies = str.Dependencies
+	return nil
+}
+
+// MarshalYAML uses the short notation when the implementation has no dependency
+func (o OperationImplementation) MarshalYAML() (interface{}, error) {
+	if len(o.Dependencies) == 0 {
+		return o.Primary, nil
+	}
+	return struct {
+		Primary      string   `yaml:"primary"`
+		Dependencies []string `yaml:"dependencies"`
+	}{o.Primary, o.Dependencies}, nil
+}
+
+// MarshalJSON uses the short notation when the implementation has no dependency,
+// so the JSON of a simple implementation is the string it was before OperationImplementation
+func (o OperationImplementation) MarshalJSON() ([]byte, error) {
+	if len(o.Dependencies) == 0 {
+		return json.Marshal(o.Primary)
+	}
+	return json.Marshal(struct {
+		Primary      string   `json:"primary"`
+		Dependencies []string `json:"dependencies"`
+	}{o.Primary, o.Dependencies})
+}
+
+// UnmarshalJSON accepts both the short notation (the primary artifact) and the object notation
+func (o *OperationImplementation) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		o.Primary = s
+		o.Dependencies = nil
+		return nil
+	}
+	var str struct {
+		Primary      string   `json:"primary"`
+		Dependencies []string `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	o.Primary = str.Primary
+	o.Dependencies = str.Dependencies
+	return nil
+}
+
+// String returns the primary artifact, the former string value of Implementation
+func (o OperationImplementation) String() string {
+	return o.Primary
+}
+
+// Artifacts returns the primary artifact followed by its dependencies
+func (o OperationImplementation) Artifacts() []string {
+	if o.Primary == "" {
+		return o.Dependencies
+	}
+	return append([]string{o.Primary}, o.Dependencies...)
+}
+
 //type PropertyDefinition struct { }
 
 // InterfaceDefinition TODO: Appendix 5.12
 
 // InterfaceDefinition is related to a node type
-type InterfaceDefinition map[string]InterfaceDef
+type InterfaceDefinition struct {
+	Type       string                  `yaml:"type,omitempty" json:"type,omitempty"`     // The optional name of the interface type the definition is based upon.
+	Inputs     map[string]Input        `yaml:"inputs,omitempty" json:"inputs,omitempty"` // The optional inputs of all the operations of the interface.
+	Operations map[string]InterfaceDef `yaml:"operations,inline" json:"operations"`      // The operations of the interface by name.
+}
+
+// clone returns a copy of the definition that does not share its inputs and operations
+func (i InterfaceDefinition) clone() InterfaceDefinition {
+	out := InterfaceDefinition{Type: i.Type}
+	if i.Inputs != nil {
+		out.Inputs = make(map[string]Input, len(i.Inputs))
+		for k, v := range i.Inputs {
+			out.Inputs[k] = v
+		}
+	}
+	if i.Operations != nil {
+		out.Operations = make(map[string]InterfaceDef, len(i.Operations))
+		for k, v := range i.Operations {
+			out.Operations[k] = v
+		}
+	}
+	return out
+}
+
 type InterfaceDef struct {
-	Inputs         map[string]Input `yaml:"inputs,omitempty"`
-	Description    string           `yaml:"description,omitempty"`
-	Implementation string           `yaml:"implementation,omitempty"`
+	Inputs         map[string]Input        `yaml:"inputs,omitempty"`
+	Description    string                  `yaml:"description,omitempty"`
+	Implementation OperationImplementation `yaml:"implementation,omitempty"`
 }
 
 func (i *InterfaceDef) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var s string
 	if err := unmarshal(&s); err == nil {
-		i.Implementation = s
+		i.Implementation = OperationImplementation{Primary: s}
 		return nil
 	}
 	var str struct {
 		Inputs map[string]Input `yaml:"inputs,omitempty"`
 		//Implementation      string                 `yaml:"implementation,omitempty"`
-		Description    string `yaml:"description,omitempty"`
-		Implementation string `yaml:"implementation,omitempty"`
+		Description    string                  `yaml:"description,omitempty"`
+		Implementation OperationImplementation `yaml:"implementation,omitempty"`
 	}
 	if err := unmarshal(&str); err != nil {
 		return err
@@ -87,3 +247,64 @@ func (i *InterfaceDef) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	i.Description = str.Description
 	return nil
 }
+
+// EffectiveInterfaces returns the interfaces of the node template named node: the interfaces
+// inherited from its node type hierarchy on which the operations of the template are applied.
+// An operation of the template overrides the description and the implementation of the inherited
+// operation if it sets them, and its inputs are merged into the inherited ones.
+// The inputs assigned a function by the template hold the result of the function.
+func (s *ServiceTemplateDefinition) EffectiveInterfaces(node string) (map[string]InterfaceDefinition, error) {
+	return s.effectiveInterfaces(node, nil)
+}
+
+// effectiveInterfaces is EffectiveInterfaces evaluating the inputs of the operations against ctx
+func (s *ServiceTemplateDefinition) effectiveInterfaces(node string, ctx *Context) (map[string]InterfaceDefinition, error) {
+	nt := s.GetNodeTemplate(node)
+	if nt == nil {
+		return nil, fmt.Errorf("Node template %v not found", node)
+	}
+	typ, err := s.FlattenNodeType(nt.Type)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]InterfaceDefinition, len(typ.Interfaces)+len(nt.Interfaces))
+	for name, def := range typ.Interfaces {
+		res[name] = def
+	}
+	for name, it := range nt.Interfaces {
+		def := res[name]
+		ops := make(map[string]InterfaceDef, len(def.Operations)+len(it.Operations))
+		for op, d := range def.Operations {
+			ops[op] = d
+		}
+		def.Operations = ops
+		res[name] = def
+		for op, od := range it.Operations {
+			d := ops[op]
+			if od.Description != "" {
+				d.Description = od.Description
+			}
+			if len(od.Implementation.Artifacts()) > 0 {
+				d.Implementation = od.Implementation
+			}
+			if len(od.Inputs) > 0 {
+				inputs := make(map[string]Input, len(d.Inputs)+len(od.Inputs))
+				for k, v := range d.Inputs {
+					inputs[k] = v
+				}
+				for k, pa := range od.Inputs {
+					v, err := s.evaluate(PA{PA: pa, Origin: node}, ctx)
+					if err != nil {
+						return nil, fmt.Errorf("Node %v: interface %v: operation %v: input %v: %v", node, name, op, k, err)
+					}
+					in := inputs[k]
+					in.Value = fmt.Sprint(v)
+					inputs[k] = in
+				}
+				d.Inputs = inputs
+			}
+			ops[op] = d
+		}
+	}
+	return res, nil
+}
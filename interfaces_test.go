@@ -0,0 +1,222 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"testing"
+)
+
+const implementationTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.SoftwareComponent
+    interfaces:
+      Standard:
+        create: scripts/create.sh
+        configure:
+          implementation:
+            primary: scripts/configure.sh
+            dependencies:
+              - scripts/common.sh
+              - files/app.conf
+topology_template:
+  node_templates:
+    app:
+      type: my.nodes.App
+`
+
+func TestOperationImplementation(t *testing.T) {
+	s := parseString(t, implementationTemplate)
+	std := s.NodeTypes["my.nodes.App"].Interfaces["Standard"]
+	create := std.Operations["create"].Implementation
+	if create.Primary != "scripts/create.sh" || len(create.Dependencies) != 0 {
+		t.Fatalf("bad implementation of create %+v", create)
+	}
+	configure := std.Operations["configure"].Implementation
+	expected := []string{"scripts/configure.sh", "scripts/common.sh", "files/app.conf"}
+	if !reflect.DeepEqual(configure.Artifacts(), expected) {
+		t.Fatalf("expected the artifacts %v, got %v", expected, configure.Artifacts())
+	}
+	op := s.TopologyTemplate.NodeTemplates["app"].Interfaces["Standard"].Operations["configure"]
+	if !reflect.DeepEqual(op.Implementation, configure) {
+		t.Fatalf("the node template should inherit the implementation %+v, got %+v", configure, op.Implementation)
+	}
+}
+
+func TestOperationImplementationMarshal(t *testing.T) {
+	for _, in := range []string{
+		"scripts/create.sh\n",
+		"primary: scripts/configure.sh\ndependencies:\n- scripts/common.sh\n",
+	} {
+		var impl OperationImplementation
+		if err := yaml.Unmarshal([]byte(in), &impl); err != nil {
+			t.Fatal(err)
+		}
+		out, err := yaml.Marshal(impl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != in {
+			t.Errorf("expected %q, got %q", in, out)
+		}
+	}
+}
+
+func TestOperationImplementationJSON(t *testing.T) {
+	for _, in := range []string{
+		`"scripts/create.sh"`,
+		`{"primary":"scripts/configure.sh","dependencies":["scripts/common.sh"]}`,
+	} {
+		var impl OperationImplementation
+		if err := json.Unmarshal([]byte(in), &impl); err != nil {
+			t.Fatal(err)
+		}
+		out, err := json.Marshal(impl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != in {
+			t.Errorf("expected %s, got %s", in, out)
+		}
+	}
+}
+
+const interfaceTypesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+interface_types:
+  my.interfaces.Backup:
+    derived_from: tosca.interfaces.Root
+    description: Backup operations
+    backup:
+      description: Saves the data
+    restore:
+      description: Restores the data
+  my.interfaces.VerifiedBackup:
+    derived_from: my.interfaces.Backup
+    verify:
+      description: Checks a backup
+node_types:
+  my.nodes.Database:
+    derived_from: tosca.nodes.Root
+    interfaces:
+      Backup:
+        type: my.interfaces.VerifiedBackup
+        inputs:
+          target:
+            type: string
+        backup: scripts/backup.sh
+        restore: scripts/restore.sh
+  my.nodes.Broken:
+    derived_from: tosca.nodes.Root
+    interfaces:
+      Maintenance:
+        type: my.interfaces.Unknown
+topology_template:
+  node_templates:
+    db:
+      type: my.nodes.Database
+`
+
+func TestInterfaceType(t *testing.T) {
+	s := parseString(t, interfaceTypesTemplate)
+	it, err := s.InterfaceType("my.interfaces.VerifiedBackup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.DerivedFrom != "my.interfaces.Backup" || it.Description != "Backup operations" {
+		t.Fatalf("bad interface type %+v", it)
+	}
+	if len(it.Operations) != 3 || it.Operations["backup"].Description != "Saves the data" || it.Operations["verify"].Description != "Checks a backup" {
+		t.Fatalf("bad operations %+v", it.Operations)
+	}
+	if _, err := s.InterfaceType("my.interfaces.Unknown"); err == nil {
+		t.Fatal("an unknown interface type should not be found")
+	}
+	if intf := s.NodeTypes["my.nodes.Database"].Interfaces["Backup"]; intf.Type != "my.interfaces.VerifiedBackup" {
+		t.Fatalf("bad interface type name %v", intf.Type)
+	}
+}
+
+func TestInterfaceDefinitionKeynames(t *testing.T) {
+	s := parseString(t, interfaceTypesTemplate)
+	intfs, err := s.EffectiveInterfaces("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup := intfs["Backup"]
+	if backup.Type != "my.interfaces.VerifiedBackup" || backup.Inputs["target"].Type != "string" {
+		t.Fatalf("bad interface definition %+v", backup)
+	}
+	// The type and inputs keynames are not operations
+	if len(backup.Operations) != 2 || backup.Operations["backup"].Implementation.Primary != "scripts/backup.sh" {
+		t.Fatalf("expected the operations backup and restore, got %v", backup.Operations)
+	}
+}
+
+func TestValidateInterfaceTypes(t *testing.T) {
+	s := parseString(t, interfaceTypesTemplate)
+	expectErrors(t, s.Validate(), []string{"my.nodes.Broken", "Maintenance", "my.interfaces.Unknown"})
+}
+
+func TestEffectiveInterfaces(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.SoftwareComponent
+    interfaces:
+      Standard:
+        create:
+          implementation: create.sh
+          inputs:
+            port: 80
+            mode: fast
+        configure: configure.sh
+topology_template:
+  node_templates:
+    app:
+      type: my.nodes.App
+      interfaces:
+        Standard:
+          create:
+            inputs:
+              port: 8080
+`)
+	intfs, err := s.EffectiveInterfaces("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := intfs["Standard"].Operations["create"]
+	if create.Implementation.Primary != "create.sh" {
+		t.Errorf("the inherited implementation should be kept, got %v", create.Implementation)
+	}
+	if create.Inputs["port"].Value != "8080" {
+		t.Errorf("the input port should be overridden, got %v", create.Inputs["port"].Value)
+	}
+	if create.Inputs["mode"].Value != "fast" {
+		t.Errorf("the inherited input mode should be kept, got %v", create.Inputs["mode"].Value)
+	}
+	if intfs["Standard"].Operations["configure"].Implementation.Primary != "configure.sh" {
+		t.Errorf("the inherited operation configure is missing, got %v", intfs["Standard"])
+	}
+	if intfs["Standard"].Type != "tosca.interfaces.node.lifecycle.Standard" {
+		t.Errorf("the interface type should be inherited, got %q", intfs["Standard"].Type)
+	}
+	if _, err := s.EffectiveInterfaces("missing"); err == nil {
+		t.Error("an unknown node template should fail")
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonTypes maps the TOSCA primitive types to their JSON Schema type
+var jsonTypes = map[string]string{
+	"string":    "string",
+	"integer":   "integer",
+	"float":     "number",
+	"boolean":   "boolean",
+	"timestamp": "string",
+	"version":   "string",
+	"range":     "array",
+	"list":      "array",
+	"map":       "object",
+}
+
+// scalarPatterns holds the regular expressions validating the scalar-unit types.
+// They are derived from the ones used by Scalar, in the syntax of JSON Schema.
+var scalarPatterns = map[string]string{
+	"scalar-unit.size":      jsonPattern(isSize),
+	"scalar-unit.frequency": jsonPattern(isFrequency),
+	"scalar-unit.time":      jsonPattern(isDuration),
+}
+
+func jsonPattern(re *regexp.Regexp) string {
+	return strings.Replace(re.String(), "[[:blank:]]", "[ \\t]", -1)
+}
+
+// InputsJSONSchema returns a JSON Schema document describing the inputs of the topology template.
+// The TOSCA types and constraints are translated into their JSON Schema equivalent;
+// the scalar-unit inputs are strings validated by a pattern.
+func (s *ServiceTemplateDefinition) InputsJSONSchema() ([]byte, error) {
+	properties := make(map[string]interface{}, len(s.TopologyTemplate.Inputs))
+	required := []string{}
+	for name, input := range s.TopologyTemplate.Inputs {
+		schema, err := inputSchema(input)
+		if err != nil {
+			return nil, fmt.Errorf("Input %v: %v", name, err)
+		}
+		properties[name] = schema
+		if input.Required && !input.HasDefault() {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":    "http://json-schema.org/draft-07/schema#",
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}, "", "  ")
+}
+
+// inputSchema returns the JSON Schema of the input definition
+func inputSchema(input PropertyDefinition) (map[string]interface{}, error) {
+	schema := typeSchema(input.Type)
+	if input.Type == "list" || input.Type == "map" {
+		if t := entrySchemaType(input.EntrySchema); t != "" {
+			key := "items"
+			if input.Type == "map" {
+				key = "additionalProperties"
+			}
+			schema[key] = typeSchema(t)
+		}
+	}
+	if input.Description != "" {
+		schema["description"] = input.Description
+	}
+	if input.HasDefault() {
+		schema["default"] = jsonValue(input.Type, input.Default)
+	}
+	_, scalar := scalarPatterns[input.Type]
+	for _, c := range input.Constraints {
+		if err := constraintSchema(schema, c, scalar); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// typeSchema returns the JSON Schema of the TOSCA type t
+func typeSchema(t string) map[string]interface{} {
+	if pattern, ok := scalarPatterns[t]; ok {
+		return map[string]interface{}{"type": "string", "pattern": pattern}
+	}
+	switch t {
+	case "timestamp":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "range":
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}, "minItems": 2, "maxItems": 2}
+	}
+	if jt, ok := jsonTypes[t]; ok {
+		return map[string]interface{}{"type": jt}
+	}
+	// Complex data types
+	return map[string]interface{}{"type": "object"}
+}
+
+// jsonValue converts the value v read as a string into the JSON value of the TOSCA type t
+func jsonValue(t string, v string) interface{} {
+	switch t {
+	case "integer":
+		if i, err := strconv.Atoi(v); err == nil {
+			return i
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// constraintSchema adds the JSON Schema keywords of the constraint c to schema.
+// The bounds of the scalar-units cannot be expressed on strings and are ignored.
+func constraintSchema(schema map[string]interface{}, c ConstraintClause, scalar bool) error {
+	keywords := map[string]string{
+		"greater_than":     "exclusiveMinimum",
+		"greater_or_equal": "minimum",
+		"less_than":        "exclusiveMaximum",
+		"less_or_equal":    "maximum",
+		"min_length":       "minLength",
+		"max_length":       "maxLength",
+	}
+	switch c.Operator {
+	case "equal":
+		schema["const"] = c.Values
+	case "valid_values":
+		schema["enum"] = c.Values
+	case "pattern":
+		schema["pattern"] = c.Values
+	case "length":
+		schema["minLength"] = c.Values
+		schema["maxLength"] = c.Values
+	case "in_range":
+		bounds, ok := c.Values.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return fmt.Errorf("in_range expects 2 values, got %v", c.Values)
+		}
+		if !scalar {
+			schema["minimum"] = bounds[0]
+			if bounds[1] != "UNBOUNDED" {
+				schema["maximum"] = bounds[1]
+			}
+		}
+	default:
+		keyword, ok := keywords[c.Operator]
+		if !ok {
+			return fmt.Errorf("Unknown constraint %v", c.Operator)
+		}
+		if !scalar {
+			schema[keyword] = c.Values
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+const inputsSchemaTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    cpus:
+      type: integer
+      description: Number of CPUs for the server.
+      default: 1
+      constraints:
+        - in_range: [ 1, 4 ]
+    mem_size:
+      type: scalar-unit.size
+      description: Memory of the server.
+      constraints:
+        - greater_or_equal: 512 MB
+    flavor:
+      type: string
+      required: false
+      constraints:
+        - valid_values: [ small, medium, large ]
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+`
+
+func TestInputsJSONSchema(t *testing.T) {
+	s := parseString(t, inputsSchemaTemplate)
+	schema, err := s.InputsJSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := ioutil.ReadFile("tests/inputs_schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(schema, bytes.TrimSpace(golden)) {
+		t.Fatalf("the schema does not match tests/inputs_schema.json:\n%s", schema)
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Diagnostic is a problem reported by a lint rule
+type Diagnostic struct {
+	Line    int    // The line of the YAML source where the problem is located, 0 if it is unknown
+	Message string // The description of the problem
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("line %v: %v", d.Line, d.Message)
+}
+
+// LintRule checks a convention on a service template and reports its violations
+type LintRule func(*ServiceTemplateDefinition) []Diagnostic
+
+// DefaultLintRules are the rules run by Lint when none is given
+var DefaultLintRules = []LintRule{
+	LintOrphanNodes,
+	LintUnresolvedInputs,
+	LintUnknownUnits,
+}
+
+// Lint runs the rules on the service template and returns their diagnostics in the order of the rules.
+// If no rule is given, the DefaultLintRules are run.
+func (s *ServiceTemplateDefinition) Lint(rules ...LintRule) []Diagnostic {
+	if len(rules) == 0 {
+		rules = DefaultLintRules
+	}
+	var diags []Diagnostic
+	for _, rule := range rules {
+		diags = append(diags, rule(s)...)
+	}
+	return diags
+}
+
+// diagnostic returns the diagnostic msg located at the YAML node path
+func (s *ServiceTemplateDefinition) diagnostic(msg string, path ...string) Diagnostic {
+	return Diagnostic{Line: s.line(path...), Message: msg}
+}
+
+// LintOrphanNodes reports the node templates that neither have requirements nor are the target of a requirement
+func LintOrphanNodes(s *ServiceTemplateDefinition) []Diagnostic {
+	linked := make(map[string]bool)
+	for name, node := range s.TopologyTemplate.NodeTemplates {
+		for _, req := range node.Requirements {
+			for _, ra := range req {
+				linked[name] = true
+				linked[ra.Node] = true
+			}
+		}
+	}
+	var diags []Diagnostic
+	if len(s.TopologyTemplate.NodeTemplates) < 2 {
+		return diags
+	}
+	for _, name := range s.nodeNames() {
+		if !linked[name] {
+			diags = append(diags, s.diagnostic(fmt.Sprintf("Node %v is not related to any other node", name), "topology_template", "node_templates", name))
+		}
+	}
+	return diags
+}
+
+// LintUnresolvedInputs reports the get_input functions referencing an input that is not declared
+func LintUnresolvedInputs(s *ServiceTemplateDefinition) []Diagnostic {
+	var diags []Diagnostic
+	check := func(v interface{}, path ...string) {
+		var missing []string
+		walkFunctions(v, func(fn string, args []interface{}) {
+			if fn != "get_input" || len(args) == 0 {
+				return
+			}
+			input := fmt.Sprint(args[0])
+			if _, ok := s.TopologyTemplate.Inputs[input]; !ok {
+				missing = append(missing, input)
+			}
+		})
+		for _, input := range missing {
+			diags = append(diags, s.diagnostic(fmt.Sprintf("Input %v is not declared", input), path...))
+		}
+	}
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		for _, prop := range sortedPropertyNames(node.Properties) {
+			check(map[string][]interface{}(node.Properties[prop]), "topology_template", "node_templates", name, "properties", prop)
+		}
+		for _, capName := range sortedKeys(node.Capabilities) {
+			check(node.Capabilities[capName], "topology_template", "node_templates", name, "capabilities", capName)
+		}
+	}
+	outputs := make([]string, 0, len(s.TopologyTemplate.Outputs))
+	for name := range s.TopologyTemplate.Outputs {
+		outputs = append(outputs, name)
+	}
+	sort.Strings(outputs)
+	for _, name := range outputs {
+		check(s.TopologyTemplate.Outputs[name].Value, "topology_template", "outputs", name)
+	}
+	return diags
+}
+
+// UnusedInputs returns the sorted names of the inputs of the topology template that are never referenced,
+// neither by a get_input function nor by a property mapping of the substitution mappings
+func (s *ServiceTemplateDefinition) UnusedInputs() []string {
+	used := make(map[string]bool)
+	collect := func(v interface{}) {
+		walkFunctions(v, func(fn string, args []interface{}) {
+			if fn == "get_input" && len(args) > 0 {
+				used[fmt.Sprint(args[0])] = true
+			}
+		})
+	}
+	assignments := func(props map[string]PropertyAssignment) {
+		for _, prop := range props {
+			collect(map[string][]interface{}(prop))
+		}
+	}
+	for _, node := range s.TopologyTemplate.NodeTemplates {
+		assignments(node.Properties)
+		collect(node.Capabilities)
+		for _, iface := range node.Interfaces {
+			for _, op := range iface.Operations {
+				assignments(op.Inputs)
+			}
+		}
+	}
+	for _, rel := range s.TopologyTemplate.RelationshipTemplates {
+		assignments(rel.Properties)
+	}
+	for _, output := range s.TopologyTemplate.Outputs {
+		collect(output.Value)
+	}
+	for _, mapping := range s.TopologyTemplate.SubstitutionMappings.Properties {
+		if len(mapping) == 1 {
+			used[mapping[0]] = true
+		}
+	}
+	var unused []string
+	for name := range s.TopologyTemplate.Inputs {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// LintUnknownUnits reports the scalar-unit properties of the node templates whose value is not a valid scalar,
+// such as a size with an unknown unit
+func LintUnknownUnits(s *ServiceTemplateDefinition) []Diagnostic {
+	var diags []Diagnostic
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		nt, err := s.FlattenNodeType(node.Type)
+		if err != nil {
+			continue
+		}
+		for _, prop := range sortedPropertyNames(node.Properties) {
+			if _, ok := baseUnits[ScalarKind(nt.Properties[prop].Type)]; !ok {
+				continue
+			}
+			v, ok := node.Properties[prop]["value"]
+			if !ok || len(v) != 1 || v[0] == nil {
+				continue
+			}
+			if _, zero := zeroScalar(v[0], ScalarKind(nt.Properties[prop].Type)); zero {
+				continue
+			}
+			if _, err := Scalar(fmt.Sprint(v[0])).Evaluate(); err != nil {
+				diags = append(diags, s.diagnostic(fmt.Sprintf("Node %v: property %v: %v", name, prop, err), "topology_template", "node_templates", name, "properties", prop))
+			}
+		}
+	}
+	return diags
+}
+
+// walkFunctions calls fn for each function found in the value v, nested functions included
+func walkFunctions(v interface{}, fn func(name string, args []interface{})) {
+	switch v := v.(type) {
+	case map[string][]interface{}:
+		for name, args := range v {
+			fn(name, args)
+			walkFunctions(args, fn)
+		}
+	case map[string]interface{}:
+		for name, args := range v {
+			list, _ := args.([]interface{})
+			if list == nil {
+				list = []interface{}{args}
+			}
+			fn(name, list)
+			walkFunctions(args, fn)
+		}
+	case map[interface{}]interface{}:
+		for name, args := range v {
+			list, _ := args.([]interface{})
+			if list == nil {
+				list = []interface{}{args}
+			}
+			fn(fmt.Sprint(name), list)
+			walkFunctions(args, fn)
+		}
+	case []interface{}:
+		for _, arg := range v {
+			walkFunctions(arg, fn)
+		}
+	}
+}
+
+// sortedPropertyNames returns the sorted names of the property assignments props
+func sortedPropertyNames(props map[string]PropertyAssignment) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+const lintTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    port:
+      type: integer
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    web:
+      type: tosca.nodes.WebServer
+      properties:
+        component_version: { get_input: version }
+      requirements:
+        - host: server
+    storage:
+      type: tosca.nodes.BlockStorage
+      properties:
+        size: 10 GB
+    backup:
+      type: tosca.nodes.BlockStorage
+      properties:
+        size: 10 GiBs
+  outputs:
+    url:
+      value: { concat: [ "http://", { get_input: host }, ":", { get_input: port } ] }
+`
+
+// expectDiagnostics fails if diags does not hold exactly the expected messages
+func expectDiagnostics(t *testing.T, diags []Diagnostic, expected ...string) {
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %v diagnostics, got %v", len(expected), diags)
+	}
+	for i, d := range diags {
+		if !strings.Contains(d.Message, expected[i]) {
+			t.Errorf("diagnostic %q should contain %q", d, expected[i])
+		}
+	}
+}
+
+func TestLintOrphanNodes(t *testing.T) {
+	s := parseString(t, lintTemplate)
+	diags := s.Lint(LintOrphanNodes)
+	expectDiagnostics(t, diags, "Node backup", "Node storage")
+	if diags[0].Line != 19 {
+		t.Errorf("the diagnostic should be located at line 19, got %v", diags[0].Line)
+	}
+}
+
+func TestLintUnresolvedInputs(t *testing.T) {
+	s := parseString(t, lintTemplate)
+	expectDiagnostics(t, s.Lint(LintUnresolvedInputs), "Input version", "Input host")
+}
+
+func TestLintUnknownUnits(t *testing.T) {
+	s := parseString(t, lintTemplate)
+	expectDiagnostics(t, s.Lint(LintUnknownUnits), "Node backup: property size: Unknown unit GiBs")
+}
+
+func TestLintCustomRule(t *testing.T) {
+	s := parseString(t, lintTemplate)
+	noDescription := func(s *ServiceTemplateDefinition) []Diagnostic {
+		if s.Description == "" {
+			return []Diagnostic{{Message: "The template has no description"}}
+		}
+		return nil
+	}
+	expectDiagnostics(t, s.Lint(noDescription, LintUnknownUnits), "no description", "Unknown unit")
+	if len(s.Lint()) != 5 {
+		t.Errorf("the default rules should report 5 diagnostics, got %v", s.Lint())
+	}
+}
+
+func TestUnusedInputs(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    size:
+      type: scalar-unit.size
+    zone:
+      type: string
+  node_templates:
+    storage:
+      type: tosca.nodes.BlockStorage
+      properties:
+        size: { get_input: size }
+`)
+	unused := s.UnusedInputs()
+	if len(unused) != 1 || unused[0] != "zone" {
+		t.Fatalf("only the input zone should be unused, got %v", unused)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NodeFilter as described in Appendix 5.4
+// A node filter definition defines criteria for selection of a TOSCA Node Template based upon the template’s property values, capabilities and capability properties.
+type NodeFilter struct {
+	Properties   []map[string]PropertyFilter   `yaml:"properties,omitempty" json:"properties,omitempty"`     // An optional sequenced list of property filters that would be used to select (filter) matching TOSCA entities (e.g., Node Template, Node Type, Capability Types, etc.) based upon their property definitions’ values.
+	Capabilities []map[string]CapabilityFilter `yaml:"capabilities,omitempty" json:"capabilities,omitempty"` // An optional sequenced list of property filters that would be used to select (filter) matching TOSCA entities (e.g., Node Template, Node Type, Capability Types, etc.) based upon their capabilities’ property definitions’ values.
+}
+
+// CapabilityFilter filters the properties of a capability in a node filter
+type CapabilityFilter struct {
+	Properties []map[string]PropertyFilter `yaml:"properties,omitempty" json:"properties,omitempty"`
+}
+
+// PropertyFilter as described in Appendix 5.3
+// A property filter is a single constraint clause or a list of constraint clauses.
+type PropertyFilter Constraints
+
+// UnmarshalYAML accepts both a single constraint clause and a list of constraint clauses
+func (p *PropertyFilter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var c ConstraintClause
+	if err := unmarshal(&c); err == nil {
+		*p = PropertyFilter{c}
+		return nil
+	}
+	var cs Constraints
+	if err := unmarshal(&cs); err != nil {
+		return err
+	}
+	*p = PropertyFilter(cs)
+	return nil
+}
+
+func (n NodeFilter) isEmpty() bool {
+	return len(n.Properties) == 0 && len(n.Capabilities) == 0
+}
+
+// unmet returns the constraints of the filter that are not satisfied by the node template named node
+func (s *ServiceTemplateDefinition) unmet(n NodeFilter, node string) ([]string, error) {
+	var unmet []string
+	props, err := s.EffectiveProperties(node)
+	if err != nil {
+		return nil, err
+	}
+	for _, filter := range n.Properties {
+		for prop, constraints := range filter {
+			v, ok := s.propertyValue(props[prop], node)
+			if !ok {
+				unmet = append(unmet, fmt.Sprintf("property %v is not set", prop))
+				continue
+			}
+			if err := Constraints(constraints).check(v); err != nil {
+				unmet = append(unmet, fmt.Sprintf("property %v: %v", prop, err))
+			}
+		}
+	}
+	nt := s.TopologyTemplate.NodeTemplates[node]
+	for _, filter := range n.Capabilities {
+		for capName, capFilter := range filter {
+			assignment, _ := nt.Capabilities[capName].(map[interface{}]interface{})
+			capProps, _ := assignment["properties"].(map[interface{}]interface{})
+			for _, propFilter := range capFilter.Properties {
+				for prop, constraints := range propFilter {
+					v, ok := capProps[prop]
+					if !ok {
+						unmet = append(unmet, fmt.Sprintf("capability %v: property %v is not set", capName, prop))
+						continue
+					}
+					if err := Constraints(constraints).check(v); err != nil {
+						unmet = append(unmet, fmt.Sprintf("capability %v: property %v: %v", capName, prop, err))
+					}
+				}
+			}
+		}
+	}
+	return unmet, nil
+}
+
+// propertyValue returns the value of the property assignment pa of the node template node,
+// evaluating its function if any. It returns false if the property is not set.
+func (s *ServiceTemplateDefinition) propertyValue(pa PropertyAssignment, node string) (interface{}, bool) {
+	if pa.IsNull() {
+		return nil, false
+	}
+	v, err := s.EvaluateStatement(PA{PA: pa, Origin: node})
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// filterNode returns the first node template, in alphabetical order, of the type nodeType that
+// satisfies the node filter n. The source node template is excluded.
+// The error lists the constraints unmet by each candidate.
+func (s *ServiceTemplateDefinition) filterNode(n NodeFilter, nodeType, source string) (string, error) {
+	var reasons []string
+	for _, name := range s.nodeNames() {
+		if name == source {
+			continue
+		}
+		if nodeType != "" && !s.isNodeType(s.TopologyTemplate.NodeTemplates[name].Type, nodeType) {
+			continue
+		}
+		unmet, err := s.unmet(n, name)
+		if err != nil {
+			return "", err
+		}
+		if len(unmet) == 0 {
+			return name, nil
+		}
+		sort.Strings(unmet)
+		reasons = append(reasons, fmt.Sprintf("%v (%v)", name, strings.Join(unmet, "; ")))
+	}
+	if len(reasons) == 0 {
+		return "", fmt.Errorf("no node template of type %v", nodeType)
+	}
+	return "", fmt.Errorf("no node template satisfies the node filter: %v", strings.Join(reasons, ", "))
+}
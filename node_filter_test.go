@@ -0,0 +1,102 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+const nodeFilterTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    small:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            num_cpus: 1
+            mem_size: 1 GB
+    large:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            num_cpus: 8
+            mem_size: 16 GB
+    db:
+      type: tosca.nodes.DBMS
+      properties:
+        port: 5432
+    app:
+      type: tosca.nodes.SoftwareComponent
+      requirements:
+        - host:
+            node_filter:
+              capabilities:
+                - host:
+                    properties:
+                      - num_cpus: { in_range: [ 2, 8 ] }
+                      - mem_size: [ { greater_or_equal: 4 GB } ]
+    app_db:
+      type: tosca.nodes.SoftwareComponent
+      requirements:
+        - dependency:
+            node: tosca.nodes.DBMS
+            node_filter:
+              properties:
+                - port: { equal: 5432 }
+    huge:
+      type: tosca.nodes.SoftwareComponent
+      requirements:
+        - host:
+            node_filter:
+              capabilities:
+                - host:
+                    properties:
+                      - num_cpus: { greater_or_equal: 16 }
+`
+
+func TestNodeFilter(t *testing.T) {
+	s := parseString(t, nodeFilterTemplate)
+	m, err := s.MatchRequirements("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].Target != "large" || m[0].Capability != "host" {
+		t.Fatalf("the host requirement should select large, got %+v", m)
+	}
+	m, err = s.MatchRequirements("app_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].Target != "db" {
+		t.Fatalf("the dependency requirement should select db, got %+v", m)
+	}
+}
+
+func TestNodeFilterUnsatisfiable(t *testing.T) {
+	s := parseString(t, nodeFilterTemplate)
+	_, err := s.MatchRequirements("huge")
+	if err == nil {
+		t.Fatal("no node should satisfy the filter")
+	}
+	for _, expected := range []string{"large (capability host: property num_cpus", "small (capability host: property num_cpus", "greater_or_equal 16"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("the error should contain %q, got %v", expected, err)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package toscalib
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // NodeTemplate as described in Appendix 7.3
@@ -26,18 +27,20 @@ type NodeTemplate struct {
 	Name         string
 	Type         string                             `yaml:"type" json:"type"`                                              // The required name of the Node Type the Node Template is based upon.
 	Decription   string                             `yaml:"description,omitempty" json:"description,omitempty"`            // An optional description for the Node Template.
+	Metadata     map[string]string                  `yaml:"metadata,omitempty" json:"metadata,omitempty"`                  // Defines a section used to declare additional metadata information.
 	Directives   []string                           `yaml:"directives,omitempty" json:"-" json:"directives,omitempty"`     // An optional list of directive values to provide processing instructions to orchestrators and tooling.
 	Properties   map[string]PropertyAssignment      `yaml:"properties,omitempty" json:"-" json:"properties,omitempty"`     // An optional list of property value assignments for the Node Template.
 	Attributes   map[string]AttributeAssignment     `yaml:"attributes,omitempty" json:"-" json:"attributes,omitempty"`     // An optional list of attribute value assignments for the Node Template.
 	Requirements []map[string]RequirementAssignment `yaml:"requirements,omitempty" json:"-" json:"requirements,omitempty"` // An optional sequenced list of requirement assignments for the Node Template.
 	Capabilities map[string]interface{}             `yaml:"capabilities,omitempty" json:"-" json:"capabilities,omitempty"` // An optional list of capability assignments for the Node Template.
 	Interfaces   map[string]InterfaceType           `yaml:"interfaces,omitempty" json:"-" json:"interfaces,omitempty"`     // An optional list of named interface definitions for the Node Template.
-	Artifcats    map[string]ArtifactDefinition      `yaml:"artifcats,omitempty" json:"-" json:"artifcats,omitempty"`       // An optional list of named artifact definitions for the Node Template.
-	NodeFilter   map[string]NodeFilter              `yaml:"node_filter,omitempty" json:"-" json:"node_filter,omitempty"`   // The optional filter definition that TOSCA orchestrators would use to select the correct target node.  This keyname is only valid if the directive has the value of “selectable” set.
+	Artifcats    map[string]ArtifactDefinition      `yaml:"artifacts,omitempty" json:"-" json:"artifacts,omitempty"`       // An optional list of named artifact definitions for the Node Template.
+	NodeFilter   NodeFilter                         `yaml:"node_filter,omitempty" json:"-" json:"node_filter,omitempty"`   // The optional filter definition that TOSCA orchestrators would use to select the correct target node.  This keyname is only valid if the directive has the value of “selectable” set.
+	Copy         string                             `yaml:"copy,omitempty" json:"copy,omitempty"`                          // The optional (symbolic) name of another node template to copy into (all keynames and values) and use as a basis for this node template.
 	Refs         struct {
-		Type       NodeType        `yaml:"-",json:"-"`
-		Interfaces []InterfaceType `yaml:"-",json:"-"`
-	} `yaml:"-",json:"-"`
+		Type       NodeType        `yaml:"-" json:"-"`
+		Interfaces []InterfaceType `yaml:"-" json:"-"`
+	} `yaml:"-" json:"-"`
 }
 
 // setRefs fills in the references of the node
@@ -76,7 +79,7 @@ func (n *NodeTemplate) fillInterface(s ServiceTemplateDefinition) {
 		var intfType InterfaceType
 		for intfname, intftype := range nt.Interfaces {
 			operations := make(map[string]OperationDefinition, 0)
-			for opname, interfacedef := range intftype {
+			for opname, interfacedef := range intftype.Operations {
 				var op OperationDefinition
 				op.Description = interfacedef.Description
 				//op.Inputs = interfacedef.Inputs
@@ -96,21 +99,173 @@ func (n *NodeTemplate) fillInterface(s ServiceTemplateDefinition) {
 		if re.MatchString(ifacename) {
 			for op, _ := range iface.Operations {
 				v, ok := intf.Operations[op]
-				_, ok2 := intf2[op]
+				_, ok2 := intf2.Operations[op]
 				switch {
 				case !ok && ok2:
-					operations[op] = OperationDefinition{nil, intf2[op].Description, intf2[op].Implementation}
+					operations[op] = OperationDefinition{nil, intf2.Operations[op].Description, intf2.Operations[op].Implementation}
 				case ok:
 					operations[op] = v
 				default:
 				}
-				tmp := InterfaceType{n.Interfaces[name].Description, n.Interfaces[name].Version, operations, n.Interfaces[name].Inputs}
+				tmp := n.Interfaces[name]
+				tmp.Operations = operations
 				n.Interfaces[name] = tmp
 			}
 		}
 	}
 }
 
+// resolveCopies replaces each node template using the copy keyname by a copy of its source
+// template on which its own definitions are applied.
+func (s *ServiceTemplateDefinition) resolveCopies() error {
+	resolved := make(map[string]bool)
+	for name := range s.TopologyTemplate.NodeTemplates {
+		if err := s.resolveCopy(name, resolved, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveCopy resolves the copy of the node template name; path holds the templates being resolved
+func (s *ServiceTemplateDefinition) resolveCopy(name string, resolved map[string]bool, path []string) error {
+	if resolved[name] {
+		return nil
+	}
+	for i, n := range path {
+		if n == name {
+			return fmt.Errorf("Node %v: cyclic copy %v", name, strings.Join(append(path[i:], name), " -> "))
+		}
+	}
+	node := s.TopologyTemplate.NodeTemplates[name]
+	if node.Copy != "" {
+		if _, ok := s.TopologyTemplate.NodeTemplates[node.Copy]; !ok {
+			return fmt.Errorf("Node %v: copy of unknown node template %v", name, node.Copy)
+		}
+		if err := s.resolveCopy(node.Copy, resolved, append(path, name)); err != nil {
+			return err
+		}
+		s.TopologyTemplate.NodeTemplates[name] = s.TopologyTemplate.NodeTemplates[node.Copy].override(node)
+	}
+	resolved[name] = true
+	return nil
+}
+
+// override returns a copy of the node template n on which the definitions of child are applied.
+// The requirements of child replace the requirements of n with the same name.
+func (n NodeTemplate) override(child NodeTemplate) NodeTemplate {
+	res := n
+	res.Copy = child.Copy
+	if child.Type != "" {
+		res.Type = child.Type
+	}
+	if child.Decription != "" {
+		res.Decription = child.Decription
+	}
+	if child.Directives != nil {
+		res.Directives = child.Directives
+	}
+	res.Metadata = make(map[string]string, len(n.Metadata)+len(child.Metadata))
+	for k, v := range n.Metadata {
+		res.Metadata[k] = v
+	}
+	for k, v := range child.Metadata {
+		res.Metadata[k] = v
+	}
+	res.Properties = make(map[string]PropertyAssignment, len(n.Properties)+len(child.Properties))
+	for k, v := range n.Properties {
+		res.Properties[k] = v
+	}
+	for k, v := range child.Properties {
+		res.Properties[k] = v
+	}
+	res.Attributes = make(map[string]AttributeAssignment, len(n.Attributes)+len(child.Attributes))
+	for k, v := range n.Attributes {
+		res.Attributes[k] = v
+	}
+	for k, v := range child.Attributes {
+		res.Attributes[k] = v
+	}
+	res.Capabilities = make(map[string]interface{}, len(n.Capabilities)+len(child.Capabilities))
+	for k, v := range n.Capabilities {
+		res.Capabilities[k] = v
+	}
+	for k, v := range child.Capabilities {
+		res.Capabilities[k] = v
+	}
+	res.Interfaces = make(map[string]InterfaceType, len(n.Interfaces)+len(child.Interfaces))
+	for k, v := range n.Interfaces {
+		res.Interfaces[k] = v
+	}
+	for k, v := range child.Interfaces {
+		res.Interfaces[k] = v
+	}
+	res.Artifcats = make(map[string]ArtifactDefinition, len(n.Artifcats)+len(child.Artifcats))
+	for k, v := range n.Artifcats {
+		res.Artifcats[k] = v
+	}
+	for k, v := range child.Artifcats {
+		res.Artifcats[k] = v
+	}
+	if !child.NodeFilter.isEmpty() {
+		res.NodeFilter = child.NodeFilter
+	}
+	overridden := make(map[string]bool)
+	for _, req := range child.Requirements {
+		for name := range req {
+			overridden[name] = true
+		}
+	}
+	res.Requirements = nil
+	for _, req := range n.Requirements {
+		for name, ra := range req {
+			if !overridden[name] {
+				res.Requirements = append(res.Requirements, map[string]RequirementAssignment{name: ra})
+			}
+		}
+	}
+	res.Requirements = append(res.Requirements, child.Requirements...)
+	return res
+}
+
 func (n *NodeTemplate) setName(name string) {
 	n.Name = name
 }
+
+// setNulls replaces the properties assigned a null value by an explicit null assignment
+func (n *NodeTemplate) setNulls() {
+	for name, p := range n.Properties {
+		if p == nil {
+			n.Properties[name] = nullAssignment()
+		}
+	}
+}
+
+// EffectiveProperties returns the properties of the node template named node:
+// the values assigned in the template completed with the default values of its node type.
+// A property explicitly assigned a null value is kept null (see PropertyAssignment.IsNull)
+// rather than taking the default value.
+func (s *ServiceTemplateDefinition) EffectiveProperties(node string) (map[string]PropertyAssignment, error) {
+	nt := s.GetNodeTemplate(node)
+	if nt == nil {
+		return nil, fmt.Errorf("Node template %v not found", node)
+	}
+	flat, err := s.FlattenNodeType(nt.Type)
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]PropertyAssignment, len(flat.Properties))
+	for name, def := range flat.Properties {
+		if def.HasDefault() {
+			props[name] = PropertyAssignment{"value": []interface{}{def.Default}}
+		}
+	}
+	for name, p := range nt.Properties {
+		if p.IsNull() {
+			props[name] = nullAssignment()
+			continue
+		}
+		props[name] = p
+	}
+	return props, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+const nullTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Server:
+    derived_from: tosca.nodes.Root
+    properties:
+      port:
+        type: integer
+        default: 8080
+      protocol:
+        type: string
+        default: tcp
+      path:
+        type: string
+        required: false
+topology_template:
+  node_templates:
+    server:
+      type: my.nodes.Server
+      properties:
+        port: null
+        path: /api
+`
+
+func TestEffectivePropertiesNull(t *testing.T) {
+	s := parseString(t, nullTemplate)
+	props, err := s.EffectiveProperties("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, ok := props["port"]
+	if !ok {
+		t.Fatal("an explicit null should not remove the property")
+	}
+	if !port.IsNull() {
+		t.Fatalf("port should be null, got %v", port)
+	}
+	if props["protocol"].IsNull() || props["protocol"]["value"][0] != "tcp" {
+		t.Fatalf("protocol should take its default value, got %v", props["protocol"])
+	}
+	if props["path"]["value"][0] != "/api" {
+		t.Fatalf("path should keep its assigned value, got %v", props["path"])
+	}
+	if _, err := s.EffectiveProperties("unknown"); err == nil {
+		t.Fatal("an unknown node should not have properties")
+	}
+}
+
+const copyTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    db:
+      type: tosca.nodes.Compute
+    web:
+      type: tosca.nodes.WebServer
+      description: The main web server
+      properties:
+        component_version: 2.4
+        admin_credential: admin
+      requirements:
+        - host: server
+        - dependency: db
+    web_backup:
+      copy: web
+      properties:
+        component_version: 2.2
+      requirements:
+        - host: db
+    web_backup_copy:
+      copy: web_backup
+`
+
+func TestNodeTemplateCopy(t *testing.T) {
+	s := parseString(t, copyTemplate)
+	for _, name := range []string{"web_backup", "web_backup_copy"} {
+		n := s.TopologyTemplate.NodeTemplates[name]
+		if n.Type != "tosca.nodes.WebServer" || n.Decription != "The main web server" || n.Name != name {
+			t.Fatalf("%v: the definitions of web should be copied, got %+v", name, n)
+		}
+		if v := n.Properties["component_version"]["value"][0]; v != "2.2" {
+			t.Errorf("%v: component_version should be overridden, got %v", name, v)
+		}
+		if v := n.Properties["admin_credential"]["value"][0]; v != "admin" {
+			t.Errorf("%v: admin_credential should be copied, got %v", name, v)
+		}
+		if len(n.Requirements) != 2 || n.Requirements[0]["dependency"].Node != "db" || n.Requirements[1]["host"].Node != "db" {
+			t.Errorf("%v: bad requirements %v", name, n.Requirements)
+		}
+	}
+	if v := s.TopologyTemplate.NodeTemplates["web"].Properties["component_version"]["value"][0]; v != "2.4" {
+		t.Errorf("the source template should not be modified, got %v", v)
+	}
+}
+
+func TestNodeTemplateCopyErrors(t *testing.T) {
+	for _, doc := range []string{
+		`tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    web:
+      copy: missing
+`,
+		`tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    a:
+      copy: b
+    b:
+      copy: a
+`,
+	} {
+		var s ServiceTemplateDefinition
+		err := s.Parse(strings.NewReader(doc))
+		if err == nil {
+			t.Errorf("parsing should fail:\n%v", doc)
+		}
+	}
+}
+
+func TestNodeMetadata(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Server:
+    derived_from: tosca.nodes.Compute
+    metadata:
+      role: backend
+topology_template:
+  node_templates:
+    server:
+      type: my.nodes.Server
+      metadata:
+        role: database
+        zone: eu-west-1a
+    replica:
+      copy: server
+      metadata:
+        zone: eu-west-1b
+`)
+	server := s.GetNodeTemplate("server")
+	if server.Metadata["role"] != "database" || server.Metadata["zone"] != "eu-west-1a" {
+		t.Errorf("unexpected metadata of server: %v", server.Metadata)
+	}
+	replica := s.GetNodeTemplate("replica")
+	if replica.Metadata["role"] != "database" || replica.Metadata["zone"] != "eu-west-1b" {
+		t.Errorf("the metadata of replica should override the copied ones: %v", replica.Metadata)
+	}
+	nt, err := s.FlattenNodeType("my.nodes.Server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nt.Metadata["role"] != "backend" {
+		t.Errorf("unexpected metadata of my.nodes.Server: %v", nt.Metadata)
+	}
+}
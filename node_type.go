@@ -17,6 +17,7 @@ package toscalib
 
 import (
 	"fmt"
+	"sync"
 )
 
 // NodeType as described is Appendix 6.8.
@@ -24,6 +25,7 @@ import (
 type NodeType struct {
 	DerivedFrom  string                             `yaml:"derived_from,omitempty" json:"derived_from"`           // An optional parent Node Type name this new Node Type derives from
 	Description  string                             `yaml:"description,omitempty" json:"description"`             // An optional description for the Node Type
+	Metadata     map[string]string                  `yaml:"metadata,omitempty" json:"metadata,omitempty"`         // Defines a section used to declare additional metadata information.
 	Properties   map[string]PropertyDefinition      `yaml:"properties,omitempty" json:"properties,omitempty"`     // An optional list of property definitions for the Node Type.
 	Attributes   map[string]AttributeDefinition     `yaml:"attributes,omitempty" json:"attributes,omitempty"`     // An optional list of attribute definitions for the Node Type.
 	Requirements []map[string]RequirementDefinition `yaml:"requirements,omitempty" json:"requirements,omitempty"` // An optional sequenced list of requirement definitions for the Node Type
@@ -39,3 +41,206 @@ func (n *NodeType) getInterface() (string, InterfaceDefinition, error) {
 	}
 	return "", InterfaceDefinition{}, fmt.Errorf("No Interface found")
 }
+
+// FlattenNodeType returns the node type named name with all the definitions
+// inherited from its ancestors merged in. The definitions of a type override
+// the ones of its parent.
+// The flattened types are cached; each call returns a copy that the caller may modify.
+// The cache is cleared when the template is parsed, ClearTypeCache must be called
+// after any other change of the type definitions.
+// It is safe for concurrent use.
+func (s *ServiceTemplateDefinition) FlattenNodeType(name string) (NodeType, error) {
+	typeCacheMu.Lock()
+	nt, ok := s.flatNodeTypes[name]
+	typeCacheMu.Unlock()
+	if ok {
+		return nt.clone(), nil
+	}
+	nt, err := s.flattenNodeType(name)
+	if err != nil {
+		return nt, err
+	}
+	typeCacheMu.Lock()
+	if s.flatNodeTypes == nil {
+		s.flatNodeTypes = make(map[string]NodeType)
+	}
+	s.flatNodeTypes[name] = nt
+	typeCacheMu.Unlock()
+	return nt.clone(), nil
+}
+
+// typeCacheMu guards the caches of the flattened types of all the templates
+var typeCacheMu sync.Mutex
+
+// ClearTypeCache empties the cache of the flattened types.
+// It must be called when the type definitions are modified after the first call to FlattenNodeType
+func (s *ServiceTemplateDefinition) ClearTypeCache() {
+	typeCacheMu.Lock()
+	s.flatNodeTypes = nil
+	typeCacheMu.Unlock()
+}
+
+// clone returns a copy of n that shares none of its maps and slices with n
+func (n NodeType) clone() NodeType {
+	out := n
+	if n.Metadata != nil {
+		out.Metadata = make(map[string]string, len(n.Metadata))
+		for k, v := range n.Metadata {
+			out.Metadata[k] = v
+		}
+	}
+	if n.Properties != nil {
+		out.Properties = make(map[string]PropertyDefinition, len(n.Properties))
+		for k, v := range n.Properties {
+			out.Properties[k] = v
+		}
+	}
+	if n.Attributes != nil {
+		out.Attributes = make(map[string]AttributeDefinition, len(n.Attributes))
+		for k, v := range n.Attributes {
+			out.Attributes[k] = v
+		}
+	}
+	if n.Capabilities != nil {
+		out.Capabilities = make(map[string]CapabilityDefinition, len(n.Capabilities))
+		for k, v := range n.Capabilities {
+			out.Capabilities[k] = v
+		}
+	}
+	if n.Interfaces != nil {
+		out.Interfaces = make(map[string]InterfaceDefinition, len(n.Interfaces))
+		for k, v := range n.Interfaces {
+			out.Interfaces[k] = v.clone()
+		}
+	}
+	if n.Requirements != nil {
+		out.Requirements = make([]map[string]RequirementDefinition, len(n.Requirements))
+		for i, req := range n.Requirements {
+			out.Requirements[i] = make(map[string]RequirementDefinition, len(req))
+			for k, v := range req {
+				out.Requirements[i][k] = v
+			}
+		}
+	}
+	if n.Artifacts != nil {
+		out.Artifacts = append([]ArtifactDefinition{}, n.Artifacts...)
+	}
+	return out
+}
+
+// flattenNodeType walks the derivation chain of the node type name and merges the definitions
+func (s *ServiceTemplateDefinition) flattenNodeType(name string) (NodeType, error) {
+	var chain []NodeType
+	visited := make(map[string]bool)
+	for n := name; n != ""; {
+		if visited[n] {
+			return NodeType{}, fmt.Errorf("Node type %v: cyclic derivation through %v", name, n)
+		}
+		visited[n] = true
+		nt, ok := s.NodeTypes[n]
+		if !ok {
+			return NodeType{}, fmt.Errorf("Node type %v not found", n)
+		}
+		chain = append(chain, nt)
+		n = nt.DerivedFrom
+	}
+	var flat NodeType
+	for i := len(chain) - 1; i >= 0; i-- {
+		flat = flat.override(chain[i])
+	}
+	flat.DerivedFrom = chain[0].DerivedFrom
+	return flat, nil
+}
+
+// override returns a copy of n where the definitions of child replace or complete the ones of n
+func (n NodeType) override(child NodeType) NodeType {
+	out := NodeType{
+		DerivedFrom:  child.DerivedFrom,
+		Description:  n.Description,
+		Metadata:     child.Metadata,
+		Properties:   make(map[string]PropertyDefinition, len(n.Properties)+len(child.Properties)),
+		Attributes:   make(map[string]AttributeDefinition, len(n.Attributes)+len(child.Attributes)),
+		Capabilities: make(map[string]CapabilityDefinition, len(n.Capabilities)+len(child.Capabilities)),
+		Interfaces:   make(map[string]InterfaceDefinition, len(n.Interfaces)+len(child.Interfaces)),
+		Artifacts:    append(append([]ArtifactDefinition{}, n.Artifacts...), child.Artifacts...),
+		Copy:         child.Copy,
+	}
+	if child.Description != "" {
+		out.Description = child.Description
+	}
+	for k, v := range n.Properties {
+		out.Properties[k] = v
+	}
+	for k, v := range child.Properties {
+		out.Properties[k] = v
+	}
+	for k, v := range n.Attributes {
+		out.Attributes[k] = v
+	}
+	for k, v := range child.Attributes {
+		out.Attributes[k] = v
+	}
+	for k, v := range n.Capabilities {
+		out.Capabilities[k] = v
+	}
+	for k, v := range child.Capabilities {
+		out.Capabilities[k] = v
+	}
+	for k, v := range n.Interfaces {
+		out.Interfaces[k] = v
+	}
+	for k, v := range child.Interfaces {
+		intf := out.Interfaces[k].clone()
+		if v.Type != "" {
+			intf.Type = v.Type
+		}
+		if intf.Inputs == nil && len(v.Inputs) > 0 {
+			intf.Inputs = make(map[string]Input, len(v.Inputs))
+		}
+		for name, in := range v.Inputs {
+			intf.Inputs[name] = in
+		}
+		if intf.Operations == nil {
+			intf.Operations = make(map[string]InterfaceDef, len(v.Operations))
+		}
+		for op, def := range v.Operations {
+			intf.Operations[op] = def
+		}
+		out.Interfaces[k] = intf
+	}
+	// A requirement of the child replaces the parent's requirement of the same name
+	out.Requirements = append([]map[string]RequirementDefinition{}, n.Requirements...)
+	for _, req := range child.Requirements {
+		for name, def := range req {
+			replaced := false
+			for i, r := range out.Requirements {
+				if _, ok := r[name]; ok {
+					out.Requirements[i] = map[string]RequirementDefinition{name: def}
+					replaced = true
+				}
+			}
+			if !replaced {
+				out.Requirements = append(out.Requirements, map[string]RequirementDefinition{name: def})
+			}
+		}
+	}
+	return out
+}
+
+// getRequirement returns the definition of the requirement named name
+func (n *NodeType) getRequirement(name string) (RequirementDefinition, bool) {
+	for _, req := range n.Requirements {
+		if def, ok := req[name]; ok {
+			return def, true
+		}
+	}
+	return RequirementDefinition{}, false
+}
+
+// isNodeType returns true if the node type name is ancestor or derives from it
+func (s *ServiceTemplateDefinition) isNodeType(name, ancestor string) bool {
+	return derivesFrom(name, ancestor, func(n string) (string, bool) {
+		nt, ok := s.NodeTypes[n]
+		return nt.DerivedFrom, ok
+	})
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFlattenNodeTypeCache(t *testing.T) {
+	s := parseString(t, nullTemplate)
+	for _, name := range []string{"my.nodes.Server", "tosca.nodes.WebServer", "tosca.nodes.Compute"} {
+		uncached, err := s.flattenNodeType(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			cached, err := s.FlattenNodeType(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cached, uncached) {
+				t.Fatalf("%v: cached type %+v differs from %+v", name, cached, uncached)
+			}
+		}
+	}
+	nt, _ := s.FlattenNodeType("tosca.nodes.WebServer")
+	if _, ok := nt.Requirements[0]["dependency"]; !ok {
+		t.Fatalf("requirement dependency should be inherited from tosca.nodes.Root, got %v", nt.Requirements)
+	}
+	// Changing a definition is only visible once the cache is cleared
+	server := s.NodeTypes["my.nodes.Server"]
+	server.DerivedFrom = "tosca.nodes.WebServer"
+	s.NodeTypes["my.nodes.Server"] = server
+	nt, _ = s.FlattenNodeType("my.nodes.Server")
+	if nt.DerivedFrom != "tosca.nodes.Root" {
+		t.Fatalf("the cached type should be returned, got %v", nt.DerivedFrom)
+	}
+	s.ClearTypeCache()
+	nt, _ = s.FlattenNodeType("my.nodes.Server")
+	if nt.DerivedFrom != "tosca.nodes.WebServer" {
+		t.Fatalf("the type should be flattened again after ClearTypeCache, got %v", nt.DerivedFrom)
+	}
+	if _, err := s.FlattenNodeType("my.nodes.Unknown"); err == nil {
+		t.Fatal("an unknown node type should not be flattened")
+	}
+}
+
+func TestFlattenNodeTypeCopy(t *testing.T) {
+	s := parseString(t, nullTemplate)
+	nt, err := s.FlattenNodeType("tosca.nodes.Compute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := len(nt.Capabilities)
+	for name := range nt.Capabilities {
+		delete(nt.Capabilities, name)
+	}
+	nt.Interfaces["Standard"].Operations["injected"] = InterfaceDef{}
+	nt, err = s.FlattenNodeType("tosca.nodes.Compute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nt.Capabilities) != count || count == 0 {
+		t.Fatalf("the cached type should keep its %v capabilities, got %v", count, len(nt.Capabilities))
+	}
+	if _, ok := nt.Interfaces["Standard"].Operations["injected"]; ok {
+		t.Fatal("the cached interfaces should not be modified through a returned type")
+	}
+}
+
+func TestFlattenNodeTypeCacheParse(t *testing.T) {
+	var s ServiceTemplateDefinition
+	doc := `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Server:
+    derived_from: PARENT
+`
+	if err := s.Parse(strings.NewReader(strings.Replace(doc, "PARENT", "tosca.nodes.Compute", 1))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FlattenNodeType("my.nodes.Server"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Parse(strings.NewReader(strings.Replace(doc, "PARENT", "tosca.nodes.WebServer", 1))); err != nil {
+		t.Fatal(err)
+	}
+	nt, err := s.FlattenNodeType("my.nodes.Server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nt.DerivedFrom != "tosca.nodes.WebServer" {
+		t.Fatalf("parsing should clear the cache, got the parent %v", nt.DerivedFrom)
+	}
+}
+
+func TestFlattenNodeTypeConcurrent(t *testing.T) {
+	s := parseString(t, nullTemplate)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Validate()
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkFlattenNodeType(b *testing.B) {
+	s := parseString(b, nullTemplate)
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.flattenNodeType("tosca.nodes.WebServer")
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.FlattenNodeType("tosca.nodes.WebServer")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+)
+
+// NormalizedModel is the compiled form of a service template, as returned by Normalize:
+// the types are flattened, the defaults applied and the functions evaluated,
+// so that it can be consumed without any knowledge of the TOSCA syntax.
+type NormalizedModel struct {
+	Inputs    map[string]interface{}  // The values of the inputs of the topology, given or defined
+	NodeTypes map[string]NodeType     // The flattened node types of the node templates
+	Nodes     map[string]ResolvedNode // The node templates resolved by ResolveNode
+}
+
+// Normalize returns the normalized model of the topology, its inputs taking their value in inputs.
+// The inputs that are not given take the value or the default value of their definition.
+// It fails if a node template cannot be resolved, see ResolveNode.
+func (s *ServiceTemplateDefinition) Normalize(inputs map[string]interface{}) (*NormalizedModel, error) {
+	if missing := s.MissingRequiredInputs(inputs); len(missing) != 0 {
+		return nil, fmt.Errorf("Missing required inputs: %v", missing)
+	}
+	ctx := &Context{Inputs: inputs}
+	m := &NormalizedModel{
+		Inputs:    make(map[string]interface{}, len(s.TopologyTemplate.Inputs)),
+		NodeTypes: make(map[string]NodeType),
+		Nodes:     make(map[string]ResolvedNode, len(s.TopologyTemplate.NodeTemplates)),
+	}
+	for name := range s.TopologyTemplate.Inputs {
+		m.Inputs[name] = ctx.input(s, name)
+	}
+	for _, name := range s.nodeNames() {
+		node, err := s.ResolveNode(name, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m.NodeTypes[node.Type]; !ok {
+			// FlattenNodeType returns a copy: the model may be edited without altering the type cache
+			nt, err := s.FlattenNodeType(node.Type)
+			if err != nil {
+				return nil, err
+			}
+			m.NodeTypes[node.Type] = nt
+		}
+		m.Nodes[name] = node
+	}
+	return m, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const normalizeModelTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+capability_types:
+  my.capabilities.Listener:
+    derived_from: tosca.capabilities.Root
+    properties:
+      port:
+        type: integer
+      protocol:
+        type: string
+        default: http
+node_types:
+  my.nodes.Api:
+    derived_from: tosca.nodes.Root
+    properties:
+      name:
+        type: string
+      workers:
+        type: integer
+        default: 4
+    capabilities:
+      listener:
+        type: my.capabilities.Listener
+topology_template:
+  inputs:
+    name:
+      type: string
+    port:
+      type: integer
+      default: 8080
+  node_templates:
+    api:
+      type: my.nodes.Api
+      properties:
+        name: { get_input: name }
+      capabilities:
+        listener:
+          properties:
+            port: { get_input: port }
+`
+
+func TestNormalize(t *testing.T) {
+	s := parseString(t, normalizeModelTemplate)
+	m, err := s.Normalize(map[string]interface{}{"name": "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]interface{}{"name": "orders", "port": "8080"}; !reflect.DeepEqual(m.Inputs, expected) {
+		t.Errorf("expected the inputs %v, got %v", expected, m.Inputs)
+	}
+	api, ok := m.Nodes["api"]
+	if !ok {
+		t.Fatal("node api is missing")
+	}
+	if expected := map[string]interface{}{"name": "orders", "workers": "4"}; !reflect.DeepEqual(api.Properties, expected) {
+		t.Errorf("expected the properties %v, got %v", expected, api.Properties)
+	}
+	if expected := map[string]interface{}{"port": "8080", "protocol": "http"}; !reflect.DeepEqual(api.Capabilities["listener"], expected) {
+		t.Errorf("expected the listener properties %v, got %v", expected, api.Capabilities["listener"])
+	}
+	nt, ok := m.NodeTypes["my.nodes.Api"]
+	if !ok {
+		t.Fatal("node type my.nodes.Api is missing")
+	}
+	if _, ok := nt.Interfaces["Standard"]; !ok {
+		t.Error("the node type should be flattened with the Standard interface of tosca.nodes.Root")
+	}
+}
+
+func TestNormalizeMissingInput(t *testing.T) {
+	s := parseString(t, normalizeModelTemplate)
+	_, err := s.Normalize(nil)
+	if err == nil || !strings.Contains(err.Error(), "Missing required inputs: [name]") {
+		t.Fatalf("expected a missing input error, got %v", err)
+	}
+}
+
+func TestNormalizeCopiesTypes(t *testing.T) {
+	s := parseString(t, normalizeModelTemplate)
+	m, err := s.Normalize(map[string]interface{}{"name": "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nt := m.NodeTypes["my.nodes.Api"]
+	delete(nt.Properties, "workers")
+	delete(nt.Capabilities, "listener")
+	flat, err := s.FlattenNodeType("my.nodes.Api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := flat.Properties["workers"]; !ok {
+		t.Error("editing the model should not remove the property workers from the type")
+	}
+	if _, ok := flat.Capabilities["listener"]; !ok {
+		t.Error("editing the model should not remove the capability listener from the type")
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"sort"
+)
+
+// OperationRef references an operation of an interface of a node template and its implementation
+type OperationRef struct {
+	Node           string                  // The name of the node template
+	Interface      string                  // The name of the interface
+	Operation      string                  // The name of the operation
+	Implementation OperationImplementation // The implementation of the operation
+	Artifacts      []string                // The paths of the primary and dependency artifacts
+}
+
+// Operations returns the operations of the node templates that have an implementation,
+// sorted by node, interface and operation.
+// The implementation artifacts naming an artifact of the node template are resolved into its file.
+func (s *ServiceTemplateDefinition) Operations() []OperationRef {
+	var ops []OperationRef
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		intfNames := make([]string, 0, len(node.Interfaces))
+		for intfName := range node.Interfaces {
+			intfNames = append(intfNames, intfName)
+		}
+		sort.Strings(intfNames)
+		for _, intfName := range intfNames {
+			operations := node.Interfaces[intfName].Operations
+			opNames := make([]string, 0, len(operations))
+			for opName := range operations {
+				opNames = append(opNames, opName)
+			}
+			sort.Strings(opNames)
+			for _, opName := range opNames {
+				impl := operations[opName].Implementation
+				if impl.Primary == "" {
+					continue
+				}
+				var artifacts []string
+				for _, artifact := range impl.Artifacts() {
+					if def, ok := node.Artifcats[artifact]; ok && def.File() != "" {
+						artifact = def.File()
+					}
+					artifacts = append(artifacts, artifact)
+				}
+				ops = append(ops, OperationRef{
+					Node:           name,
+					Interface:      intfName,
+					Operation:      opName,
+					Implementation: impl,
+					Artifacts:      artifacts,
+				})
+			}
+		}
+	}
+	return ops
+}
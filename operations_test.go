@@ -0,0 +1,69 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"testing"
+)
+
+const operationsTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.SoftwareComponent
+    interfaces:
+      Standard:
+        type: tosca.interfaces.node.lifecycle.Standard
+        create: scripts/create.sh
+        configure:
+          implementation:
+            primary: configure_script
+            dependencies:
+              - scripts/common.sh
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    app:
+      type: my.nodes.App
+      artifacts:
+        configure_script: scripts/app/configure.sh
+      requirements:
+        - host: server
+`
+
+func TestOperations(t *testing.T) {
+	s := parseString(t, operationsTemplate)
+	expected := []OperationRef{
+		{
+			Node:           "app",
+			Interface:      "Standard",
+			Operation:      "configure",
+			Implementation: OperationImplementation{Primary: "configure_script", Dependencies: []string{"scripts/common.sh"}},
+			Artifacts:      []string{"scripts/app/configure.sh", "scripts/common.sh"},
+		},
+		{
+			Node:           "app",
+			Interface:      "Standard",
+			Operation:      "create",
+			Implementation: OperationImplementation{Primary: "scripts/create.sh"},
+			Artifacts:      []string{"scripts/create.sh"},
+		},
+	}
+	if ops := s.Operations(); !reflect.DeepEqual(ops, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, ops)
+	}
+}
@@ -17,16 +17,23 @@ package toscalib
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
 	"fmt"
 	"golang.org/x/tools/godoc/vfs"
 	"golang.org/x/tools/godoc/vfs/zipfs"
 	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
+	"path"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 // GetNodeTemplate returns a pointer to a node template given its name
@@ -108,6 +115,47 @@ func merge(s, t ServiceTemplateDefinition) ServiceTemplateDefinition {
 	return s
 }
 
+// duplicateType returns the name of a type defined differently in s and t.
+// A type defined identically in both (such as a file imported twice) is not a duplicate.
+func duplicateType(s, t ServiceTemplateDefinition) (string, bool) {
+	var names []string
+	for name, val := range t.DataTypes {
+		if v, ok := s.DataTypes[name]; ok && !reflect.DeepEqual(v, val) {
+			names = append(names, name)
+		}
+	}
+	for name, val := range t.NodeTypes {
+		if v, ok := s.NodeTypes[name]; ok && !reflect.DeepEqual(v, val) {
+			names = append(names, name)
+		}
+	}
+	for name, val := range t.ArtifactTypes {
+		if v, ok := s.ArtifactTypes[name]; ok && !reflect.DeepEqual(v, val) {
+			names = append(names, name)
+		}
+	}
+	for name, val := range t.RelationshipTypes {
+		if v, ok := s.RelationshipTypes[name]; ok && !reflect.DeepEqual(v, val) {
+			names = append(names, name)
+		}
+	}
+	for name, val := range t.CapabilityTypes {
+		if v, ok := s.CapabilityTypes[name]; ok && !reflect.DeepEqual(v, val) {
+			names = append(names, name)
+		}
+	}
+	for name, val := range t.InterfaceTypes {
+		if v, ok := s.InterfaceTypes[name]; ok && !reflect.DeepEqual(v, val) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
+
 // Open and parse the Csar file c
 func (t *ServiceTemplateDefinition) ParseCsar(zipfile string) error {
 
@@ -143,142 +191,281 @@ func (t *ServiceTemplateDefinition) ParseCsar(zipfile string) error {
 		return err
 
 	}
-	var std ServiceTemplateDefinition
 	data, err := ioutil.ReadAll(rsc)
 	if err != nil {
 		return err
 	}
+	return t.parse(data, func(im string) ([]byte, error) {
+		rsc, err := ns.Open(im)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(rsc)
+	}, ParseOptions{})
+}
 
-	// Unmarshal the data in an interface
-	err = yaml.Unmarshal(data, &std)
+// ParseOptions are the limits enforced when parsing a document that may not be trusted
+type ParseOptions struct {
+	MaxValueDepth int // The greatest nesting depth of the values of the topology template (see MaxValueDepth), 0 for no limit
+}
+
+// Parse a TOSCA document and fill in the structure
+func (t *ServiceTemplateDefinition) Parse(r io.Reader) error {
+	return t.ParseWithOptions(r, ParseOptions{})
+}
+
+// ParseWithOptions is like Parse and fails if the document exceeds the limits of opts
+func (t *ServiceTemplateDefinition) ParseWithOptions(r io.Reader, opts ParseOptions) error {
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return err
 	}
-	// Import de normative types by default
-	for _, normType := range []string{"interface_types", "relationship_types", "node_types", "capability_types"} {
-		data, err := Asset(normType)
+	return t.parse(data, readImport, opts)
+}
+
+// readImport reads the import im from its URL or from the local file system
+func readImport(im string) ([]byte, error) {
+	u, err := url.Parse(im)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		res, err := http.Get(u.String())
 		if err != nil {
-			return err
+			return nil, err
 		}
-		var tt ServiceTemplateDefinition
-		err = yaml.Unmarshal(data, &tt)
+		defer res.Body.Close()
+		return ioutil.ReadAll(res.Body)
+	default:
+		r, err := ioutil.ReadFile(im)
 		if err != nil {
-			return err
+			// A missing local import is not an error
+			return nil, nil
 		}
-		std = merge(std, tt)
+		return r, nil
 	}
-	for _, im := range std.Imports {
-		rsc, err := ns.Open(im)
-		if err != nil {
-			return err
+}
 
-		}
-		var tt ServiceTemplateDefinition
-		data, err := ioutil.ReadAll(rsc)
+// ParseURL fetches the TOSCA document located at rawurl with client and parses it.
+// The imports relative to the document are fetched from the same base URL.
+// If client is nil, http.DefaultClient is used.
+func ParseURL(ctx context.Context, rawurl string, client *http.Client) (*ServiceTemplateDefinition, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	base, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	get := func(u *url.URL) ([]byte, error) {
+		req, err := http.NewRequest("GET", u.String(), nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		// Unmarshal the data in an interface
-		err = yaml.Unmarshal(data, &tt)
+		res, err := client.Do(req.WithContext(ctx))
 		if err != nil {
-			return err
+			return nil, err
 		}
-		std = merge(std, tt)
-	}
-	// Free the imports
-	std.Imports = []string{}
-	*t = std
-	for name, node := range t.TopologyTemplate.NodeTemplates {
-		node.fillInterface(*t)
-		node.setRefs(t)
-		node.setName(name)
-		t.TopologyTemplate.NodeTemplates[name] = node
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Cannot fetch %v: %v", u, res.Status)
+		}
+		return ioutil.ReadAll(res.Body)
 	}
-
-	return nil
-}
-
-// Parse a TOSCA document and fill in the structure
-func (t *ServiceTemplateDefinition) Parse(r io.Reader) error {
-	var std ServiceTemplateDefinition
-	data, err := ioutil.ReadAll(r)
+	data, err := get(base)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// Unmarshal the data in an interface
-	err = yaml.Unmarshal(data, &std)
+	var t ServiceTemplateDefinition
+	err = t.parse(data, func(im string) ([]byte, error) {
+		u, err := url.Parse(im)
+		if err != nil {
+			return nil, err
+		}
+		return get(base.ResolveReference(u))
+	}, ParseOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	// Import de normative types by default
+	return &t, nil
+}
+
+// NormativeTypes returns the normative types of the TOSCA Simple Profile embedded in the library.
+// They are merged into every parsed template, so no network access is needed to use them.
+func NormativeTypes() (ServiceTemplateDefinition, error) {
+	var std ServiceTemplateDefinition
 	for _, normType := range []string{"interface_types", "relationship_types", "node_types", "capability_types"} {
 		data, err := Asset(normType)
 		if err != nil {
-			log.Panic("Normative type not found")
-			return err
+			return std, err
 		}
 		var tt ServiceTemplateDefinition
 		err = yaml.Unmarshal(data, &tt)
 		if err != nil {
-			return err
+			return std, err
 		}
 		std = merge(std, tt)
 	}
-	for _, im := range std.Imports {
-		u, err := url.Parse(im)
-		if err != nil {
-			log.Panic(err)
-		}
-		var r []byte
-		switch u.Scheme {
-		case "http":
-			res, err := http.Get(u.String())
-			if err != nil {
-				return err
+	return std, nil
+}
 
-			}
-			r, err = ioutil.ReadAll(res.Body)
-			res.Body.Close()
-			if err != nil {
-				return err
-			}
-		case "https":
-			res, err := http.Get(u.String())
-			if err != nil {
-				return err
+// normativeProfiles are the well-known imports of the normative types, by name or by file name.
+// They are satisfied by the embedded normative types and are never fetched.
+var normativeProfiles = map[string]bool{
+	"tosca_simple_yaml_1_0":     true,
+	"TOSCA_definition_1_0.yaml": true,
+	"TOSCA_definition_1_0.yml":  true,
+}
 
+// isNormativeProfile returns true if the import file is a well-known import of the normative types
+func isNormativeProfile(file string) bool {
+	return normativeProfiles[file] || normativeProfiles[path.Base(file)]
+}
+
+// utf8BOM is the byte order mark written by some editors at the beginning of the UTF-8 files
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// decodeDocument returns the YAML document data without its byte order mark, if any.
+// It fails with the line of the first invalid byte if data is not valid UTF-8.
+func decodeDocument(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			line := bytes.Count(data[:i], []byte("\n")) + 1
+			return nil, fmt.Errorf("Invalid UTF-8 byte 0x%x at line %v: the document must be encoded in UTF-8", data[i], line)
+		}
+		i += size
+	}
+	return data, nil
+}
+
+// mergeImports merges the documents of imports read by get, and their own imports, into std.
+// stack holds the chain of the files being imported and is used to detect the circular imports.
+func mergeImports(std ServiceTemplateDefinition, imports []Import, stack []string, get func(string) ([]byte, error)) (ServiceTemplateDefinition, error) {
+	for _, im := range imports {
+		if isNormativeProfile(im.File) {
+			// Already merged from the embedded normative types
+			continue
+		}
+		for i, file := range stack {
+			if file == im.File {
+				cycle := append(append([]string{}, stack[i:]...), im.File)
+				return std, fmt.Errorf("Circular import: %v", strings.Join(cycle, " -> "))
 			}
-			r, err = ioutil.ReadAll(res.Body)
-			res.Body.Close()
-			if err != nil {
-				return err
-			}
-		default:
-			r, err = ioutil.ReadFile(im)
-			if err != nil {
-				//return err
-			}
+		}
+		r, err := get(im.File)
+		if err != nil {
+			return std, err
+		}
+		r, err = decodeDocument(r)
+		if err != nil {
+			return std, fmt.Errorf("Import %v: %v", im.File, err)
 		}
 		var tt ServiceTemplateDefinition
 
 		err = yaml.Unmarshal(r, &tt)
 		if err != nil {
-			return err
+			return std, err
+		}
+		if name, ok := duplicateType(std, tt); ok {
+			return std, fmt.Errorf("Duplicate type %v in import %v", name, im.File)
 		}
 		std = merge(std, tt)
+		// The imported types may change the flattening of the known ones
+		std.ClearTypeCache()
+		std, err = mergeImports(std, tt.Imports, append(append([]string{}, stack...), im.File), get)
+		if err != nil {
+			return std, err
+		}
+	}
+	return std, nil
+}
+
+// CheckImportsReachable calls exists for each import of the document that is not a normative profile
+// and returns the errors of the imports it reports as missing, without reading nor parsing them,
+// so that all the missing imports can be reported at once.
+// Parse merges and then clears the imports: it must be called on a document decoded before Parse.
+// The imports of the imported documents are not checked.
+func (s *ServiceTemplateDefinition) CheckImportsReachable(exists func(string) error) []error {
+	var errs []error
+	for _, im := range s.Imports {
+		if isNormativeProfile(im.File) {
+			continue
+		}
+		if err := exists(im.File); err != nil {
+			errs = append(errs, fmt.Errorf("Import %v: %v", im.File, err))
+		}
+	}
+	return errs
+}
+
+// parse unmarshals data, merges the normative types and the imports read by
+// get and fills in t with the result
+func (t *ServiceTemplateDefinition) parse(data []byte, get func(string) ([]byte, error), opts ParseOptions) error {
+	var std ServiceTemplateDefinition
+
+	data, err := decodeDocument(data)
+	if err != nil {
+		return err
+	}
+	// Keep the positions of the document for the diagnostics
+	var source yaml3.Node
+	hasSource := yaml3.Unmarshal(data, &source) == nil
+	// Reject the values nested too deep before decoding them
+	if opts.MaxValueDepth > 0 && hasSource {
+		if depth := sourceValueDepth(&source); depth > opts.MaxValueDepth {
+			return valueDepthError(depth, opts.MaxValueDepth)
+		}
+	}
+	// Unmarshal the data in an interface
+	err = yaml.Unmarshal(data, &std)
+	if err != nil {
+		return err
+	}
+	// Import de normative types by default
+	normative, err := NormativeTypes()
+	if err != nil {
+		return err
+	}
+	if name, ok := duplicateType(std, normative); ok {
+		return fmt.Errorf("Duplicate type %v: it is a normative type", name)
+	}
+	std = merge(std, normative)
+	std, err = mergeImports(std, std.Imports, nil, get)
+	if err != nil {
+		return err
 	}
 	// Free the imports
-	std.Imports = []string{}
+	std.Imports = []Import{}
+	if hasSource {
+		std.source = &source
+	}
 	*t = std
+	// The types flattened for a previous document are stale
+	t.ClearTypeCache()
+	if err := t.resolveCopies(); err != nil {
+		return err
+	}
 	for name, node := range t.TopologyTemplate.NodeTemplates {
 		node.fillInterface(*t)
 		node.setRefs(t)
 		node.setName(name)
+		node.setNulls()
 		t.TopologyTemplate.NodeTemplates[name] = node
 	}
+	// The decoding wraps the arguments of the functions in lists, check the decoded values too
+	if opts.MaxValueDepth > 0 {
+		if depth := t.MaxValueDepth(); depth > opts.MaxValueDepth {
+			return valueDepthError(depth, opts.MaxValueDepth)
+		}
+	}
 
 	return nil
+}
 
+// valueDepthError reports values nested depth levels deep when limit is allowed
+func valueDepthError(depth, limit int) error {
+	return fmt.Errorf("The values of the template are nested %v levels deep, the limit is %v", depth, limit)
 }
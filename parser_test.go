@@ -0,0 +1,280 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const urlTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+imports:
+  - types/custom.yaml
+topology_template:
+  node_templates:
+    app:
+      type: my.nodes.App
+`
+
+const urlImport = `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.SoftwareComponent
+`
+
+func newTemplateServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/templates/app.yaml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, urlTemplate)
+	})
+	mux.HandleFunc("/templates/types/custom.yaml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, urlImport)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestParseURL(t *testing.T) {
+	ts := newTemplateServer()
+	defer ts.Close()
+	s, err := ParseURL(context.Background(), ts.URL+"/templates/app.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.NodeTypes["my.nodes.App"]; !ok {
+		t.Fatal("the imported node type my.nodes.App is missing")
+	}
+	if s.GetNodeTemplate("app") == nil {
+		t.Fatal("node template app not found")
+	}
+}
+
+func TestParseURLNotFound(t *testing.T) {
+	ts := newTemplateServer()
+	defer ts.Close()
+	_, err := ParseURL(context.Background(), ts.URL+"/templates/missing.yaml", ts.Client())
+	if err == nil {
+		t.Fatal("fetching a missing template should fail")
+	}
+	if !strings.Contains(err.Error(), "404") {
+		t.Fatalf("error should report the status, got %v", err)
+	}
+}
+
+func TestParseURLCanceled(t *testing.T) {
+	ts := newTemplateServer()
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ParseURL(ctx, ts.URL+"/templates/app.yaml", nil)
+	if err == nil {
+		t.Fatal("a canceled context should abort the fetch")
+	}
+}
+
+// parseString parses the TOSCA document doc and fails the test on error
+func parseString(t testing.TB, doc string) *ServiceTemplateDefinition {
+	var s ServiceTemplateDefinition
+	if err := s.Parse(strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+	return &s
+}
+
+const inlineTypeTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Cache:
+    derived_from: tosca.nodes.SoftwareComponent
+    properties:
+      size:
+        type: integer
+        default: 64
+topology_template:
+  node_templates:
+    cache:
+      type: my.nodes.Cache
+`
+
+func TestParseInlineType(t *testing.T) {
+	s := parseString(t, inlineTypeTemplate)
+	nt, err := s.FlattenNodeType(s.GetNodeTemplate("cache").Type)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nt.Properties["size"]; !ok {
+		t.Error("the inline property size is missing")
+	}
+	if _, ok := nt.Properties["component_version"]; !ok {
+		t.Error("the inherited property component_version is missing")
+	}
+}
+
+func TestParseDuplicateType(t *testing.T) {
+	doc := `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  tosca.nodes.Compute:
+    derived_from: tosca.nodes.Root
+`
+	var s ServiceTemplateDefinition
+	err := s.Parse(strings.NewReader(doc))
+	if err == nil || !strings.Contains(err.Error(), "tosca.nodes.Compute") {
+		t.Fatalf("expected a duplicate type error, got %v", err)
+	}
+}
+
+func TestParseURLDuplicateImport(t *testing.T) {
+	doc := urlTemplate + `node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.Root
+`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app.yaml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, doc)
+	})
+	mux.HandleFunc("/types/custom.yaml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, urlImport)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	_, err := ParseURL(context.Background(), ts.URL+"/app.yaml", nil)
+	if err == nil || !strings.Contains(err.Error(), "my.nodes.App") {
+		t.Fatalf("expected a duplicate type error, got %v", err)
+	}
+}
+
+// parseFiles parses the document main with the imports read from files
+func parseFiles(main string, files map[string]string) (*ServiceTemplateDefinition, error) {
+	var s ServiceTemplateDefinition
+	err := s.parse([]byte(main), func(im string) ([]byte, error) {
+		doc, ok := files[im]
+		if !ok {
+			return nil, fmt.Errorf("%v not found", im)
+		}
+		return []byte(doc), nil
+	}, ParseOptions{})
+	return &s, err
+}
+
+func TestParseNestedImports(t *testing.T) {
+	s, err := parseFiles("tosca_definitions_version: tosca_simple_yaml_1_0\nimports:\n  - a.yaml\n", map[string]string{
+		"a.yaml": "tosca_definitions_version: tosca_simple_yaml_1_0\nimports:\n  - b.yaml\n",
+		"b.yaml": urlImport,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.NodeTypes["my.nodes.App"]; !ok {
+		t.Fatal("the node type my.nodes.App imported by a.yaml is missing")
+	}
+}
+
+func TestParseCircularImports(t *testing.T) {
+	_, err := parseFiles("tosca_definitions_version: tosca_simple_yaml_1_0\nimports:\n  - a.yaml\n", map[string]string{
+		"a.yaml": "tosca_definitions_version: tosca_simple_yaml_1_0\nimports:\n  - b.yaml\n",
+		"b.yaml": "tosca_definitions_version: tosca_simple_yaml_1_0\nimports:\n  - a.yaml\n",
+	})
+	if err == nil || !strings.Contains(err.Error(), "a.yaml -> b.yaml -> a.yaml") {
+		t.Fatalf("expected a circular import error, got %v", err)
+	}
+}
+
+func TestParseNormativeProfileImport(t *testing.T) {
+	s, err := parseFiles(`tosca_definitions_version: tosca_simple_yaml_1_0
+imports:
+  - tosca_simple_yaml_1_0
+  - https://docs.oasis-open.org/tosca/TOSCA-Simple-Profile-YAML/v1.0/TOSCA_definition_1_0.yaml
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    web:
+      type: tosca.nodes.WebServer
+      requirements:
+        - host: server
+`, nil)
+	if err != nil {
+		t.Fatalf("the normative profile should not be fetched: %v", err)
+	}
+	if errs := s.Validate(); len(errs) != 0 {
+		t.Fatalf("the template should be valid, got %v", errs)
+	}
+	normative, err := NormativeTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"tosca.nodes.Compute", "tosca.nodes.WebServer"} {
+		if _, ok := normative.NodeTypes[name]; !ok {
+			t.Errorf("the normative type %v is missing", name)
+		}
+	}
+}
+
+func TestParseBOM(t *testing.T) {
+	s := parseString(t, "\xef\xbb\xbf"+inlineTypeTemplate)
+	if s.GetNodeTemplate("cache") == nil {
+		t.Fatal("node template cache not found")
+	}
+	if line := s.line("topology_template", "node_templates", "cache"); line != 11 {
+		t.Fatalf("the byte order mark should not shift the lines, got %v", line)
+	}
+}
+
+func TestParseInvalidUTF8(t *testing.T) {
+	var s ServiceTemplateDefinition
+	err := s.Parse(strings.NewReader("tosca_definitions_version: tosca_simple_yaml_1_0\ndescription: caf\xe9\n"))
+	if err == nil || !strings.Contains(err.Error(), "Invalid UTF-8 byte 0xe9 at line 2") {
+		t.Fatalf("expected an invalid UTF-8 error, got %v", err)
+	}
+	_, err = parseFiles("tosca_definitions_version: tosca_simple_yaml_1_0\nimports:\n  - a.yaml\n", map[string]string{
+		"a.yaml": "\xef\xbb\xbf" + "tosca_definitions_version: tosca_simple_yaml_1_0\ndescription: \xff\n",
+	})
+	if err == nil || !strings.Contains(err.Error(), "Import a.yaml: Invalid UTF-8 byte 0xff at line 2") {
+		t.Fatalf("expected an invalid UTF-8 error in the import, got %v", err)
+	}
+}
+
+func TestCheckImportsReachable(t *testing.T) {
+	var s ServiceTemplateDefinition
+	err := yaml.Unmarshal([]byte(`tosca_definitions_version: tosca_simple_yaml_1_0
+imports:
+  - tosca_simple_yaml_1_0
+  - types/network.yaml
+  - types/storage.yaml
+`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checked []string
+	errs := s.CheckImportsReachable(func(file string) error {
+		checked = append(checked, file)
+		if file == "types/storage.yaml" {
+			return errors.New("file not found")
+		}
+		return nil
+	})
+	if expected := []string{"types/network.yaml", "types/storage.yaml"}; !reflect.DeepEqual(checked, expected) {
+		t.Errorf("expected the imports %v to be checked, got %v", expected, checked)
+	}
+	if len(errs) != 1 || errs[0].Error() != "Import types/storage.yaml: file not found" {
+		t.Fatalf("expected the storage import to be missing, got %v", errs)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PolicyDefinition as described in section 3.7.6: a policy applied to node templates of the topology
+type PolicyDefinition struct {
+	Type        string                        `yaml:"type" json:"type"`                                   // The required name of the policy type the policy definition is based upon.
+	Description string                        `yaml:"description,omitempty" json:"description,omitempty"` // The optional description for the policy definition.
+	Metadata    map[string]string             `yaml:"metadata,omitempty" json:"metadata,omitempty"`       // Defines a section used to declare additional metadata information.
+	Properties  map[string]PropertyAssignment `yaml:"properties,omitempty" json:"properties,omitempty"`   // An optional list of property value assignments for the policy definition.
+	Targets     []string                      `yaml:"targets,omitempty" json:"targets,omitempty"`         // An optional list of valid node templates the policy applies to.
+	Triggers    map[string]Trigger            `yaml:"triggers,omitempty" json:"triggers,omitempty"`       // An optional list of trigger definitions to invoke when the policy is applied.
+}
+
+// Trigger is the definition of an event, the condition on which it is raised and the action to take.
+// The condition constrains attributes of the node templates selected by the target filter,
+// or of the targets of the policy if the trigger has no target filter.
+type Trigger struct {
+	Description  string           `yaml:"description,omitempty" json:"description,omitempty"`     // The optional description of the trigger.
+	Event        string           `yaml:"event" json:"event"`                                     // The required name of the event that activates the trigger.
+	TargetFilter EventFilter      `yaml:"target_filter,omitempty" json:"target_filter,omitempty"` // The optional filter selecting the node template the condition applies to.
+	Condition    TriggerCondition `yaml:"condition,omitempty" json:"condition,omitempty"`         // The optional condition that must be satisfied to run the action.
+	Action       []Activity       `yaml:"action" json:"action"`                                   // The required list of activities run when the trigger is activated.
+}
+
+// EventFilter selects the node template, and optionally its capability, whose attributes an event is about
+type EventFilter struct {
+	Node        string `yaml:"node,omitempty" json:"node,omitempty"`               // The name of the node template.
+	Requirement string `yaml:"requirement,omitempty" json:"requirement,omitempty"` // The optional name of a requirement of the node template.
+	Capability  string `yaml:"capability,omitempty" json:"capability,omitempty"`   // The optional name of a capability of the node template.
+}
+
+// TriggerCondition holds the constraints on attributes that activate a trigger,
+// such as a cpu_load attribute greater than 80 on average over a period of 60 s
+type TriggerCondition struct {
+	Constraint  map[string]Constraints `yaml:"constraint,omitempty" json:"constraint,omitempty"`   // The constraints by name of the attribute they apply to.
+	Period      Scalar                 `yaml:"period,omitempty" json:"period,omitempty"`           // The optional period of time the constraints are evaluated on.
+	Evaluations int                    `yaml:"evaluations,omitempty" json:"evaluations,omitempty"` // The optional number of evaluations that must satisfy the constraints.
+	Method      string                 `yaml:"method,omitempty" json:"method,omitempty"`           // The optional aggregation of the evaluations, such as average.
+}
+
+// Activity is a step of the action of a trigger. Exactly one of its keynames is expected.
+type Activity struct {
+	CallOperation string `yaml:"call_operation,omitempty" json:"call_operation,omitempty"` // The operation to call, given as interface.operation.
+	Delegate      string `yaml:"delegate,omitempty" json:"delegate,omitempty"`             // The name of a workflow to delegate to.
+	Inline        string `yaml:"inline,omitempty" json:"inline,omitempty"`                 // The name of a workflow to inline.
+	SetState      string `yaml:"set_state,omitempty" json:"set_state,omitempty"`           // The state to set the target node templates to.
+}
+
+// normativeWorkflows are the workflows every topology provides
+var normativeWorkflows = map[string]bool{
+	"deploy":   true,
+	"undeploy": true,
+}
+
+// checkTrigger returns the errors of the trigger t of a policy applied to targets:
+// the attributes of its condition and the operations and workflows of its action must be defined.
+func (s *ServiceTemplateDefinition) checkTrigger(t Trigger, targets []string) (condition, action []error) {
+	nodes := targets
+	if t.TargetFilter.Node != "" {
+		nodes = []string{t.TargetFilter.Node}
+	}
+	for _, attr := range sortedConstraintNames(t.Condition.Constraint) {
+		for _, node := range nodes {
+			args := []interface{}{node, attr}
+			if t.TargetFilter.Capability != "" {
+				args = []interface{}{node, t.TargetFilter.Capability, attr}
+			}
+			if err := s.checkAttributeReference(args); err != nil {
+				condition = append(condition, err)
+			}
+		}
+	}
+	for _, a := range t.Action {
+		switch {
+		case a.CallOperation != "":
+			i := strings.LastIndex(a.CallOperation, ".")
+			if i < 0 {
+				action = append(action, fmt.Errorf("operation %v is not of the form interface.operation", a.CallOperation))
+				continue
+			}
+			for _, node := range nodes {
+				ok, err := s.hasOperation(node, a.CallOperation[:i], a.CallOperation[i+1:])
+				if err != nil {
+					action = append(action, err)
+				} else if !ok {
+					action = append(action, fmt.Errorf("operation %v is not defined by node %v", a.CallOperation, node))
+				}
+			}
+		case a.Delegate != "" || a.Inline != "":
+			workflow := a.Delegate + a.Inline
+			if _, ok := s.TopologyTemplate.Workflows[workflow]; !ok && !normativeWorkflows[workflow] {
+				action = append(action, fmt.Errorf("workflow %v not found", workflow))
+			}
+		case a.SetState == "":
+			action = append(action, fmt.Errorf("activity without call_operation, delegate, inline or set_state"))
+		}
+	}
+	return condition, action
+}
+
+// hasOperation returns true if the node template node has the operation op in its interface iface,
+// given by its name or by the name of its interface type.
+// The operations may be defined by the node template, its node type or the interface type.
+func (s *ServiceTemplateDefinition) hasOperation(node, iface, op string) (bool, error) {
+	ifaces, err := s.EffectiveInterfaces(node)
+	if err != nil {
+		return false, err
+	}
+	for name, def := range ifaces {
+		if name != iface && def.Type != iface {
+			continue
+		}
+		if _, ok := def.Operations[op]; ok {
+			return true, nil
+		}
+		it, err := s.InterfaceType(def.Type)
+		if err != nil {
+			return false, err
+		}
+		if _, ok := it.Operations[op]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sortedConstraintNames returns the sorted keys of c
+func sortedConstraintNames(c map[string]Constraints) []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
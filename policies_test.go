@@ -0,0 +1,90 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+const scalingPolicyTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Server:
+    derived_from: tosca.nodes.Compute
+    attributes:
+      cpu_load:
+        type: float
+topology_template:
+  node_templates:
+    server:
+      type: my.nodes.Server
+  workflows:
+    scale_out:
+      description: Adds a server
+  policies:
+    - autoscale:
+        type: tosca.policies.Scaling
+        targets: [ server ]
+        triggers:
+          cpu_threshold:
+            event: tosca.events.resource.utilization
+            condition:
+              constraint:
+                cpu_load: [ { greater_than: 80 } ]
+              period: 60 s
+              evaluations: 2
+              method: average
+            action:
+              - delegate: scale_out
+              - call_operation: Standard.configure
+`
+
+func TestPolicyTriggers(t *testing.T) {
+	s := parseString(t, scalingPolicyTemplate)
+	if len(s.TopologyTemplate.Policies) != 1 {
+		t.Fatalf("expected a policy, got %v", s.TopologyTemplate.Policies)
+	}
+	trigger := s.TopologyTemplate.Policies[0]["autoscale"].Triggers["cpu_threshold"]
+	if trigger.Event != "tosca.events.resource.utilization" || trigger.Condition.Period != "60 s" || trigger.Condition.Evaluations != 2 {
+		t.Fatalf("the trigger is not parsed: %+v", trigger)
+	}
+	if ok, _ := trigger.Condition.Constraint["cpu_load"].IsValid("81"); !ok {
+		t.Error("a cpu_load of 81 should activate the trigger")
+	}
+	if len(trigger.Action) != 2 || trigger.Action[0].Delegate != "scale_out" || trigger.Action[1].CallOperation != "Standard.configure" {
+		t.Fatalf("the action is not parsed: %+v", trigger.Action)
+	}
+	if errs := s.Validate(); len(errs) != 0 {
+		t.Fatalf("the template should be valid, got %v", errs)
+	}
+}
+
+func TestValidatePolicyTriggers(t *testing.T) {
+	s := parseString(t, strings.NewReplacer(
+		"cpu_load: [", "mem_load: [",
+		"delegate: scale_out", "delegate: scale_in",
+		"Standard.configure", "Standard.resize",
+		"targets: [ server ]", "targets: [ server, backup ]",
+	).Replace(scalingPolicyTemplate))
+	expectErrors(t, s.Validate(),
+		[]string{"Policy autoscale", "target backup not found"},
+		[]string{"trigger cpu_threshold: condition", "attribute mem_load is not defined by node server"},
+		[]string{"trigger cpu_threshold: condition", "node backup not found"},
+		[]string{"trigger cpu_threshold: action", "workflow scale_in not found"},
+		[]string{"trigger cpu_threshold: action", "operation Standard.resize is not defined by node server"},
+		[]string{"trigger cpu_threshold: action", "Node template backup not found"},
+	)
+}
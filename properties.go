@@ -17,6 +17,7 @@ package toscalib
 
 import (
 	"fmt"
+	"strconv"
 )
 
 // PropertyDefinition as described in Appendix 5.7:
@@ -26,7 +27,8 @@ import (
 // Properties are used by template authors to provide input values to
 // TOSCA entities which indicate their “desired state” when they are instantiated.
 // The value of a property can be retrieved using the
-// get_property function within TOSCA Service Templates
+// get_property function within TOSCA Service Templates.
+// A definition that omits the required key is required, as the specification says.
 type PropertyDefinition struct {
 	Value       string      `yaml:"value,omitempty"`
 	Type        string      `yaml:"type" json:"type"`                                   // The required data type for the property
@@ -36,6 +38,14 @@ type PropertyDefinition struct {
 	Status      Status      `yaml:"status,omitempty" json:"status,omitempty"`
 	Constraints Constraints `yaml:"constraints,omitempty,flow" json:"constraints,omitempty"`
 	EntrySchema interface{} `yaml:"entry_schema,omitempty" json:"entry_schema,omitempty"`
+
+	defaultDeclared bool // True if the default is written in the definition, even as an empty string
+}
+
+// HasDefault returns true if the definition declares a default value.
+// An explicit empty default such as default: "" is a default.
+func (p PropertyDefinition) HasDefault() bool {
+	return p.defaultDeclared || p.Default != ""
 }
 
 func (p *PropertyDefinition) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -48,8 +58,8 @@ func (p *PropertyDefinition) UnmarshalYAML(unmarshal func(interface{}) error) er
 		Value       string                 `yaml:"value,omitempty"`
 		Type        string                 `yaml:"type" json:"type"`                                   // The required data type for the property
 		Description string                 `yaml:"description,omitempty" json:"description,omitempty"` // The optional description for the property.
-		Required    bool                   `yaml:"required,omitempty" json:"required,omitempty"`       // An optional key that declares a property as required ( true) or not ( false) Default: true
-		Default     string                 `yaml:"default,omitempty" json:"default,omitempty"`
+		Required    *bool                  `yaml:"required,omitempty" json:"required,omitempty"`       // An optional key that declares a property as required ( true) or not ( false) Default: true
+		Default     *string                `yaml:"default,omitempty" json:"default,omitempty"`
 		Status      Status                 `yaml:"status,omitempty" json:"status,omitempty"`
 		Constraints Constraints            `yaml:"constraints,omitempty,flow" json:"constraints,omitempty"`
 		EntrySchema map[string]interface{} `yaml:"entry_schema,omitempty" json:"entry_schema,omitempty"`
@@ -59,8 +69,14 @@ func (p *PropertyDefinition) UnmarshalYAML(unmarshal func(interface{}) error) er
 		p.Value = test2.Value
 		p.Type = test2.Type
 		p.Description = test2.Description
-		p.Required = test2.Required
-		p.Default = test2.Default
+		p.Required = true
+		if test2.Required != nil {
+			p.Required = *test2.Required
+		}
+		if test2.Default != nil {
+			p.Default = *test2.Default
+			p.defaultDeclared = true
+		}
 		p.Status = test2.Status
 		p.Constraints = test2.Constraints
 		p.EntrySchema = test2.EntrySchema
@@ -71,9 +87,52 @@ func (p *PropertyDefinition) UnmarshalYAML(unmarshal func(interface{}) error) er
 	return fmt.Errorf("Cannot parse Property %v", res)
 }
 
+// check returns an error if the value v does not match the type or the constraints of the property.
+// The types that are neither primitive nor scalar-units are not checked.
+func (p PropertyDefinition) check(v interface{}) error {
+	str := fmt.Sprint(v)
+	switch p.Type {
+	case "integer":
+		if _, err := strconv.Atoi(str); err != nil {
+			return fmt.Errorf("%v is not an integer", v)
+		}
+	case "float":
+		if _, err := strconv.ParseFloat(str, 64); err != nil {
+			return fmt.Errorf("%v is not a float", v)
+		}
+	case "boolean":
+		if _, ok := booleans[str]; !ok {
+			return fmt.Errorf("%v is not a boolean", v)
+		}
+	case "scalar-unit.size", "scalar-unit.frequency", "scalar-unit.time":
+		if err := checkKind(v, ScalarKind(p.Type)); err != nil {
+			return err
+		}
+		if zero, ok := zeroScalar(v, ScalarKind(p.Type)); ok {
+			v = zero
+		}
+	}
+	return p.Constraints.check(v)
+}
+
 // A Property assignment is always a map, but the key may be value
 type PropertyAssignment map[string][]interface{}
 
+// nullAssignment returns the assignment of an explicit null value.
+// An explicit null overrides the default value of the property definition
+func nullAssignment() PropertyAssignment {
+	return PropertyAssignment{"value": []interface{}{nil}}
+}
+
+// IsNull returns true if the property has explicitly been assigned a null value
+func (p PropertyAssignment) IsNull() bool {
+	if p == nil {
+		return true
+	}
+	v, ok := p["value"]
+	return ok && len(p) == 1 && len(v) == 1 && v[0] == nil
+}
+
 func (p *PropertyAssignment) MarshalYAML() (interface{}, error) {
 	for k, v := range *p {
 		if k == "value" {
@@ -95,6 +154,36 @@ func (p *PropertyAssignment) UnmarshalYAML(unmarshal func(interface{}) error) er
 		(*p)["value"][0] = s
 		return nil
 	}
+	// The extended notation of a value with its own constraints, such as
+	// { value: 8080, constraints: [ { in_range: [ 1024, 65535 ] } ] }.
+	// A literal value is checked against the constraints, which are not kept.
+	// It is detected before the generic maps, which would swallow a list or map value.
+	var ext struct {
+		Value       interface{} `yaml:"value"`
+		Constraints Constraints `yaml:"constraints"`
+	}
+	var keys map[string]interface{}
+	if err := unmarshal(&keys); err == nil && len(keys) == 2 && keys["value"] != nil && keys["constraints"] != nil {
+		if err := unmarshal(&ext); err != nil {
+			return err
+		}
+		if function, ok := ext.Value.(map[interface{}]interface{}); ok {
+			// The function is evaluated later, its result cannot be checked yet
+			for k, v := range function {
+				args, ok := v.([]interface{})
+				if !ok {
+					args = []interface{}{v}
+				}
+				(*p)[fmt.Sprint(k)] = args
+			}
+			return nil
+		}
+		if err := ext.Constraints.check(ext.Value); err != nil {
+			return fmt.Errorf("Property value %v: %v", ext.Value, err)
+		}
+		(*p)["value"] = []interface{}{ext.Value}
+		return nil
+	}
 	var m map[string]string
 	if err := unmarshal(&m); err == nil {
 		for k, v := range m {
@@ -0,0 +1,74 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+const inlineConstraintsTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    port:
+      type: integer
+  node_templates:
+    web:
+      type: tosca.nodes.WebServer
+      properties:
+        component_version:
+          value: PORT
+          constraints:
+            - in_range: [ 1024, 65535 ]
+`
+
+func TestPropertyAssignmentInlineConstraints(t *testing.T) {
+	s := parseString(t, strings.Replace(inlineConstraintsTemplate, "PORT", "8080", 1))
+	v, err := s.EvaluateStatement(s.GetProperty("web", "component_version"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 8080 {
+		t.Errorf("expected 8080, got %v", v)
+	}
+	var bad ServiceTemplateDefinition
+	err = bad.Parse(strings.NewReader(strings.Replace(inlineConstraintsTemplate, "PORT", "80", 1)))
+	if err == nil || !strings.Contains(err.Error(), "Property value 80: 80 does not satisfy the constraint in_range [1024 65535]") {
+		t.Fatalf("expected a constraint violation, got %v", err)
+	}
+}
+
+func TestPropertyAssignmentInlineConstraintsFunction(t *testing.T) {
+	s := parseString(t, strings.Replace(inlineConstraintsTemplate, "PORT", "{ get_input: port }", 1))
+	pa := s.GetNodeTemplate("web").Properties["component_version"]
+	if len(pa) != 1 || pa["get_input"][0] != "port" {
+		t.Fatalf("the function should be kept for a later evaluation, got %v", pa)
+	}
+}
+
+func TestPropertyAssignmentInlineConstraintsList(t *testing.T) {
+	doc := strings.Replace(inlineConstraintsTemplate, "in_range: [ 1024, 65535 ]", "max_length: 1", 1)
+	s := parseString(t, strings.Replace(doc, "PORT", "[ a ]", 1))
+	pa := s.GetNodeTemplate("web").Properties["component_version"]
+	if list, ok := pa["value"][0].([]interface{}); len(pa) != 1 || !ok || len(list) != 1 || list[0] != "a" {
+		t.Fatalf("expected the value [a], got %v", pa)
+	}
+	var bad ServiceTemplateDefinition
+	err := bad.Parse(strings.NewReader(strings.Replace(doc, "PORT", "[ a, b, c ]", 1)))
+	if err == nil || !strings.Contains(err.Error(), "Property value [a b c]") {
+		t.Fatalf("expected a constraint violation, got %v", err)
+	}
+}
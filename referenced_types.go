@@ -0,0 +1,154 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"sort"
+)
+
+// typeRefs collects the names of the types referenced by a template
+type typeRefs struct {
+	s    *ServiceTemplateDefinition
+	seen map[string]bool
+}
+
+// ReferencedTypes returns the sorted names of the node, capability, relationship and data types
+// used by the topology template, either directly or through the definitions of the types it uses
+// (derivation, property types, capabilities and requirements).
+func (s *ServiceTemplateDefinition) ReferencedTypes() []string {
+	refs := typeRefs{s: s, seen: make(map[string]bool)}
+	for _, input := range s.TopologyTemplate.Inputs {
+		refs.dataType(input.Type)
+	}
+	for _, node := range s.TopologyTemplate.NodeTemplates {
+		refs.nodeType(node.Type)
+		for _, req := range node.Requirements {
+			for _, ra := range req {
+				if _, ok := s.NodeTypes[ra.Node]; ok {
+					refs.nodeType(ra.Node)
+				}
+				if _, ok := s.CapabilityTypes[ra.Capability]; ok {
+					refs.capabilityType(ra.Capability)
+				}
+				if _, ok := s.RelationshipTypes[ra.RelationshipName]; ok {
+					refs.relationshipType(ra.RelationshipName)
+				}
+			}
+		}
+	}
+	types := make([]string, 0, len(refs.seen))
+	for name := range refs.seen {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// visit marks the type name as referenced, it returns false if it was already visited
+func (r typeRefs) visit(name string) bool {
+	if name == "" || r.seen[name] {
+		return false
+	}
+	r.seen[name] = true
+	return true
+}
+
+func (r typeRefs) properties(props map[string]PropertyDefinition) {
+	for _, p := range props {
+		r.dataType(p.Type)
+		r.dataType(entrySchemaType(p.EntrySchema))
+	}
+}
+
+func (r typeRefs) attributes(attrs map[string]AttributeDefinition) {
+	for _, a := range attrs {
+		r.dataType(a.Type)
+		r.dataType(entrySchemaType(a.EntrySchema))
+	}
+}
+
+func (r typeRefs) nodeType(name string) {
+	if !r.visit(name) {
+		return
+	}
+	nt := r.s.NodeTypes[name]
+	r.nodeType(nt.DerivedFrom)
+	r.properties(nt.Properties)
+	r.attributes(nt.Attributes)
+	for _, c := range nt.Capabilities {
+		r.capabilityType(c.Type)
+		for _, src := range c.ValidSourceTypes {
+			r.nodeType(src)
+		}
+	}
+	for _, req := range nt.Requirements {
+		for _, def := range req {
+			r.capabilityType(def.Capability)
+			r.nodeType(def.Node)
+			r.relationshipType(def.Relationship)
+		}
+	}
+}
+
+func (r typeRefs) capabilityType(name string) {
+	if !r.visit(name) {
+		return
+	}
+	ct := r.s.CapabilityTypes[name]
+	r.capabilityType(ct.DerivedFrom)
+	r.properties(ct.Properties)
+	r.attributes(ct.Attributes)
+	for _, src := range ct.ValidSources {
+		r.nodeType(src)
+	}
+}
+
+func (r typeRefs) relationshipType(name string) {
+	if !r.visit(name) {
+		return
+	}
+	rt := r.s.RelationshipTypes[name]
+	r.relationshipType(rt.DerivedFrom)
+	r.properties(rt.Properties)
+	r.attributes(rt.Attributes)
+	for _, target := range rt.ValidTarget {
+		r.capabilityType(target)
+	}
+}
+
+func (r typeRefs) dataType(name string) {
+	if primitiveTypes[name] || !r.visit(name) {
+		return
+	}
+	dt := r.s.DataTypes[name]
+	r.dataType(dt.DerivedFrom)
+	r.properties(dt.Properties)
+}
+
+// entrySchemaType returns the type name of an entry_schema in its short (string) or extended notation
+func entrySchemaType(schema interface{}) string {
+	var t interface{}
+	switch v := schema.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		t = v["type"]
+	case map[interface{}]interface{}:
+		t = v["type"]
+	}
+	name, _ := t.(string)
+	return name
+}
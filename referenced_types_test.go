@@ -0,0 +1,82 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"testing"
+)
+
+const referencedTypesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+data_types:
+  my.datatypes.Endpoint:
+    derived_from: tosca.datatypes.Root
+    properties:
+      host:
+        type: string
+  my.datatypes.Unused:
+    derived_from: tosca.datatypes.Root
+capability_types:
+  my.capabilities.Api:
+    derived_from: tosca.capabilities.Root
+relationship_types:
+  my.relationships.CallsApi:
+    derived_from: tosca.relationships.Root
+node_types:
+  my.nodes.Service:
+    derived_from: tosca.nodes.Root
+    properties:
+      endpoint:
+        type: my.datatypes.Endpoint
+    capabilities:
+      api: my.capabilities.Api
+  my.nodes.Client:
+    derived_from: tosca.nodes.Root
+    requirements:
+      - api:
+          capability: my.capabilities.Api
+          relationship: my.relationships.CallsApi
+  my.nodes.Unused:
+    derived_from: tosca.nodes.Compute
+topology_template:
+  node_templates:
+    service:
+      type: my.nodes.Service
+    client:
+      type: my.nodes.Client
+      requirements:
+        - api: service
+`
+
+func TestReferencedTypes(t *testing.T) {
+	s := parseString(t, referencedTypesTemplate)
+	expected := []string{
+		"my.capabilities.Api",
+		"my.datatypes.Endpoint",
+		"my.nodes.Client",
+		"my.nodes.Service",
+		"my.relationships.CallsApi",
+		"tosca.capabilities.Node",
+		"tosca.capabilities.Root",
+		"tosca.datatypes.Root",
+		"tosca.nodes.Root",
+		"tosca.relationships.DependsOn",
+		"tosca.relationships.Root",
+	}
+	if types := s.ReferencedTypes(); !reflect.DeepEqual(types, expected) {
+		t.Fatalf("expected %v, got %v", expected, types)
+	}
+}
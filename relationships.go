@@ -15,6 +15,10 @@ limitations under the License.
 */
 package toscalib
 
+import (
+	"fmt"
+)
+
 // RelationshipType as described in appendix 6.9
 // A Relationship Type is a reusable entity that defines the type of one or more relationships between Node Types or Node Templates.
 // TODO
@@ -27,3 +31,67 @@ type RelationshipType struct {
 	Interfaces  map[string]InterfaceDefinition `yaml:"interfaces,omitempty" json:"interfaces"`
 	ValidTarget []string                       `yaml:"valid_target_types,omitempty" json:"valid_target_types"`
 }
+
+// RelationshipTemplate as described in Appendix 7.4
+// A Relationship Template specifies the occurrence of a manageable relationship between node templates as part of an application’s topology model.
+type RelationshipTemplate struct {
+	Type        string                         `yaml:"type" json:"type"`                                   // The required name of the Relationship Type the Relationship Template is based upon.
+	Description string                         `yaml:"description,omitempty" json:"description,omitempty"` // An optional description for the Relationship Template.
+	Properties  map[string]PropertyAssignment  `yaml:"properties,omitempty" json:"properties,omitempty"`   // An optional list of property assignments for the Relationship Template.
+	Attributes  map[string]AttributeAssignment `yaml:"attributes,omitempty" json:"attributes,omitempty"`   // An optional list of attribute assignments for the Relationship Template.
+	Interfaces  map[string]InterfaceDefinition `yaml:"interfaces,omitempty" json:"interfaces,omitempty"`   // An optional list of named interface definitions for the Relationship Template.
+	Copy        string                         `yaml:"copy,omitempty" json:"copy,omitempty"`               // The optional (symbolic) name of another relationship template to copy into (all keynames and values) and use as a basis for this relationship template.
+}
+
+// RelationshipType returns the relationship type named name with the
+// definitions and valid target types inherited from its ancestors
+func (s *ServiceTemplateDefinition) RelationshipType(name string) (RelationshipType, error) {
+	var chain []RelationshipType
+	visited := make(map[string]bool)
+	for n := name; n != ""; {
+		if visited[n] {
+			return RelationshipType{}, fmt.Errorf("Relationship type %v: cyclic derivation through %v", name, n)
+		}
+		visited[n] = true
+		rt, ok := s.RelationshipTypes[n]
+		if !ok {
+			return RelationshipType{}, fmt.Errorf("Relationship type %v not found", n)
+		}
+		chain = append(chain, rt)
+		n = rt.DerivedFrom
+	}
+	flat := RelationshipType{
+		DerivedFrom: chain[0].DerivedFrom,
+		Version:     chain[0].Version,
+		Properties:  make(map[string]PropertyDefinition),
+		Attributes:  make(map[string]AttributeDefinition),
+		Interfaces:  make(map[string]InterfaceDefinition),
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		rt := chain[i]
+		if rt.Description != "" {
+			flat.Description = rt.Description
+		}
+		for k, v := range rt.Properties {
+			flat.Properties[k] = v
+		}
+		for k, v := range rt.Attributes {
+			flat.Attributes[k] = v
+		}
+		for k, v := range rt.Interfaces {
+			flat.Interfaces[k] = v
+		}
+		if len(rt.ValidTarget) != 0 {
+			flat.ValidTarget = rt.ValidTarget
+		}
+	}
+	return flat, nil
+}
+
+// isRelationshipType returns true if the relationship type name is ancestor or derives from it
+func (s *ServiceTemplateDefinition) isRelationshipType(name, ancestor string) bool {
+	return derivesFrom(name, ancestor, func(n string) (string, bool) {
+		rt, ok := s.RelationshipTypes[n]
+		return rt.DerivedFrom, ok
+	})
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+const relationshipsTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+capability_types:
+  my.capabilities.Storage:
+    derived_from: tosca.capabilities.Root
+node_types:
+  my.nodes.Store:
+    derived_from: tosca.nodes.Root
+    capabilities:
+      store: my.capabilities.Storage
+  my.nodes.Client:
+    derived_from: tosca.nodes.Root
+    requirements:
+      - backend:
+          capability: tosca.capabilities.Root
+          relationship: tosca.relationships.ConnectsTo
+topology_template:
+  node_templates:
+    web:
+      type: tosca.nodes.WebServer
+    store:
+      type: my.nodes.Store
+    client_ok:
+      type: my.nodes.Client
+      requirements:
+        - backend: web
+    client_ko:
+      type: my.nodes.Client
+      requirements:
+        - backend: store
+    client_template_ok:
+      type: my.nodes.Client
+      requirements:
+        - backend:
+            node: store
+            capability: store
+            relationship: stores_in
+    client_template_ko:
+      type: my.nodes.Client
+      requirements:
+        - backend:
+            node: store
+            capability: store
+            relationship: connects_to
+  relationship_templates:
+    stores_in:
+      type: tosca.relationships.Root
+    connects_to:
+      type: tosca.relationships.ConnectsTo
+`
+
+func TestRelationshipType(t *testing.T) {
+	s := parseString(t, relationshipsTemplate)
+	rt, err := s.RelationshipType("tosca.relationships.RoutesTo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rt.Properties["credential"]; !ok {
+		t.Fatal("property credential should be inherited from tosca.relationships.ConnectsTo")
+	}
+	if _, ok := rt.Interfaces["Configure"]; !ok {
+		t.Fatal("interface Configure should be inherited from tosca.relationships.Root")
+	}
+	if len(rt.ValidTarget) != 1 || rt.ValidTarget[0] != "tosca.capabilities.Endpoint" {
+		t.Fatalf("bad valid target types %v", rt.ValidTarget)
+	}
+}
+
+func TestMatchValidTargetTypes(t *testing.T) {
+	s := parseString(t, relationshipsTemplate)
+	m, err := s.MatchRequirements("client_ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || !s.isCapabilityType(m[0].CapabilityType, "tosca.capabilities.Endpoint") {
+		t.Fatalf("bad match %+v", m)
+	}
+	_, err = s.MatchRequirements("client_ko")
+	if err == nil {
+		t.Fatal("ConnectsTo should not accept a storage capability as target")
+	}
+	if !strings.Contains(err.Error(), "tosca.relationships.ConnectsTo") {
+		t.Fatalf("the error should name the relationship, got %v", err)
+	}
+}
+
+func TestMatchRelationshipTemplate(t *testing.T) {
+	s := parseString(t, relationshipsTemplate)
+	m, err := s.MatchRequirements("client_template_ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].Template != "stores_in" || m[0].Relationship != "tosca.relationships.Root" || m[0].CapabilityType != "my.capabilities.Storage" {
+		t.Fatalf("bad match %+v", m)
+	}
+	_, err = s.MatchRequirements("client_template_ko")
+	if err == nil {
+		t.Fatal("the connects_to template should not accept a storage capability as target")
+	}
+	for _, expected := range []string{"connects_to", "tosca.capabilities.Endpoint", "my.capabilities.Storage"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("the error should contain %v, got %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+)
+
+// nodeFunctions are the functions whose first argument is the name of a node template
+var nodeFunctions = map[string]bool{
+	"get_property":         true,
+	"get_attribute":        true,
+	"get_operation_output": true,
+	"get_artifact":         true,
+}
+
+// RenameNode renames the node template oldName into newName and updates the references to it:
+// the targets of the requirements, the copies of the node templates, the node arguments of the functions
+// of the properties, operation inputs and outputs, the targets of the policies and the substitution mappings.
+// It fails without modifying the template if oldName does not exist or newName already exists.
+func (s *ServiceTemplateDefinition) RenameNode(oldName, newName string) error {
+	node, ok := s.TopologyTemplate.NodeTemplates[oldName]
+	if !ok {
+		return fmt.Errorf("Node %v not found", oldName)
+	}
+	if _, ok := s.TopologyTemplate.NodeTemplates[newName]; ok {
+		return fmt.Errorf("Node %v already exists", newName)
+	}
+	delete(s.TopologyTemplate.NodeTemplates, oldName)
+	node.Name = newName
+	s.TopologyTemplate.NodeTemplates[newName] = node
+	for name, n := range s.TopologyTemplate.NodeTemplates {
+		if n.Copy == oldName {
+			n.Copy = newName
+			s.TopologyTemplate.NodeTemplates[name] = n
+		}
+		for _, req := range n.Requirements {
+			for name, ra := range req {
+				if ra.Node == oldName {
+					ra.Node = newName
+					req[name] = ra
+				}
+			}
+		}
+		for _, pa := range n.Properties {
+			renameAssignment(pa, oldName, newName)
+		}
+		for _, intf := range n.Interfaces {
+			for _, op := range intf.Operations {
+				for _, pa := range op.Inputs {
+					renameAssignment(pa, oldName, newName)
+				}
+			}
+		}
+	}
+	for _, r := range s.TopologyTemplate.RelationshipTemplates {
+		for _, pa := range r.Properties {
+			renameAssignment(pa, oldName, newName)
+		}
+	}
+	for _, policies := range s.TopologyTemplate.Policies {
+		for name, policy := range policies {
+			for i, target := range policy.Targets {
+				if target == oldName {
+					policy.Targets[i] = newName
+				}
+			}
+			for _, pa := range policy.Properties {
+				renameAssignment(pa, oldName, newName)
+			}
+			for tname, trigger := range policy.Triggers {
+				if trigger.TargetFilter.Node == oldName {
+					trigger.TargetFilter.Node = newName
+					policy.Triggers[tname] = trigger
+				}
+			}
+			policies[name] = policy
+		}
+	}
+	sm := s.TopologyTemplate.SubstitutionMappings
+	for _, mappings := range []map[string][]string{sm.Capabilities, sm.Requirements, sm.Properties, sm.Attributes} {
+		for _, m := range mappings {
+			if len(m) == 2 && m[0] == oldName {
+				m[0] = newName
+			}
+		}
+	}
+	for _, o := range s.TopologyTemplate.Outputs {
+		for fn, args := range o.Value {
+			o.Value[fn] = renameArguments(fn, args, oldName, newName)
+		}
+	}
+	return nil
+}
+
+// renameAssignment replaces the node oldName by newName in the functions of the property assignment pa
+func renameAssignment(pa PropertyAssignment, oldName, newName string) {
+	for fn, args := range pa {
+		pa[fn] = renameArguments(fn, args, oldName, newName).([]interface{})
+	}
+}
+
+// renameArguments returns the arguments of the function fn where the node oldName is replaced by newName.
+// The functions nested in the arguments are renamed as well.
+func renameArguments(fn string, args interface{}, oldName, newName string) interface{} {
+	list, ok := args.([]interface{})
+	if !ok {
+		return renameValue(args, oldName, newName)
+	}
+	for i, arg := range list {
+		if i == 0 && nodeFunctions[fn] && arg == oldName {
+			list[i] = newName
+			continue
+		}
+		list[i] = renameValue(arg, oldName, newName)
+	}
+	return list
+}
+
+// renameValue renames the node in the functions of the value v, which may be a nested function call
+func renameValue(v interface{}, oldName, newName string) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		for k, args := range v {
+			v[k] = renameArguments(fmt.Sprint(k), args, oldName, newName)
+		}
+	case []interface{}:
+		for i, arg := range v {
+			v[i] = renameValue(arg, oldName, newName)
+		}
+	}
+	return v
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"testing"
+)
+
+const renameTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    web:
+      type: tosca.nodes.WebServer
+      properties:
+        component_version: { get_property: [ server, component_version ] }
+        admin_credential: { concat: [ "admin@", { get_attribute: [ server, public_address ] } ] }
+      requirements:
+        - host: server
+    app:
+      type: tosca.nodes.WebApplication
+      requirements:
+        - host: web
+  outputs:
+    address:
+      value: { get_attribute: [ server, public_address ] }
+  substitution_mappings:
+    node_type: tosca.nodes.WebServer
+    capabilities:
+      host: [ server, host ]
+`
+
+func TestRenameNode(t *testing.T) {
+	s := parseString(t, renameTemplate)
+	if err := s.RenameNode("server", "vm"); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetNodeTemplate("server") != nil {
+		t.Fatal("the node server should not exist anymore")
+	}
+	vm := s.GetNodeTemplate("vm")
+	if vm == nil || vm.Name != "vm" {
+		t.Fatalf("bad renamed node %+v", vm)
+	}
+	web := s.TopologyTemplate.NodeTemplates["web"]
+	if web.Requirements[0]["host"].Node != "vm" {
+		t.Errorf("the host requirement should target vm, got %v", web.Requirements[0]["host"].Node)
+	}
+	if v := web.Properties["component_version"]["get_property"]; !reflect.DeepEqual(v, []interface{}{"vm", "component_version"}) {
+		t.Errorf("get_property should target vm, got %v", v)
+	}
+	nested := web.Properties["admin_credential"]["concat"][1].(map[interface{}]interface{})["get_attribute"]
+	if !reflect.DeepEqual(nested, []interface{}{"vm", "public_address"}) {
+		t.Errorf("the nested get_attribute should target vm, got %v", nested)
+	}
+	if v := s.TopologyTemplate.Outputs["address"].Value["get_attribute"]; !reflect.DeepEqual(v, []interface{}{"vm", "public_address"}) {
+		t.Errorf("the output should target vm, got %v", v)
+	}
+	if v := s.TopologyTemplate.SubstitutionMappings.Capabilities["host"]; !reflect.DeepEqual(v, []string{"vm", "host"}) {
+		t.Errorf("the substitution mapping should target vm, got %v", v)
+	}
+	if s.TopologyTemplate.NodeTemplates["app"].Requirements[0]["host"].Node != "web" {
+		t.Error("the other references should be left untouched")
+	}
+}
+
+func TestRenameNodeCollision(t *testing.T) {
+	s := parseString(t, renameTemplate)
+	if err := s.RenameNode("server", "web"); err == nil {
+		t.Fatal("renaming a node to an existing name should fail")
+	}
+	if s.GetNodeTemplate("server") == nil || s.TopologyTemplate.NodeTemplates["web"].Requirements[0]["host"].Node != "server" {
+		t.Fatal("a failed rename should not modify the template")
+	}
+	if err := s.RenameNode("missing", "other"); err == nil {
+		t.Fatal("renaming a missing node should fail")
+	}
+}
+
+const renamePoliciesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    backup:
+      type: tosca.nodes.Compute
+      copy: server
+  policies:
+    - autoscale:
+        type: tosca.policies.Scaling
+        targets: [ server, backup ]
+        triggers:
+          cpu_threshold:
+            event: tosca.events.resource.utilization
+            target_filter:
+              node: server
+            action:
+              - call_operation: Standard.configure
+`
+
+func TestRenameNodePolicies(t *testing.T) {
+	s := parseString(t, renamePoliciesTemplate)
+	if err := s.RenameNode("server", "vm"); err != nil {
+		t.Fatal(err)
+	}
+	policy := s.TopologyTemplate.Policies[0]["autoscale"]
+	if !reflect.DeepEqual(policy.Targets, []string{"vm", "backup"}) {
+		t.Errorf("the targets of the policy should be renamed, got %v", policy.Targets)
+	}
+	if node := policy.Triggers["cpu_threshold"].TargetFilter.Node; node != "vm" {
+		t.Errorf("the target filter of the trigger should be renamed, got %v", node)
+	}
+	if errs := s.validatePolicies(); len(errs) != 0 {
+		t.Errorf("the renamed policy should be valid, got %v", errs)
+	}
+}
+
+func TestRenameNodeCopy(t *testing.T) {
+	s := parseString(t, renamePoliciesTemplate)
+	if err := s.RenameNode("server", "vm"); err != nil {
+		t.Fatal(err)
+	}
+	if copy := s.TopologyTemplate.NodeTemplates["backup"].Copy; copy != "vm" {
+		t.Errorf("the copy of backup should reference vm, got %q", copy)
+	}
+}
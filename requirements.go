@@ -15,15 +15,24 @@ limitations under the License.
 */
 package toscalib
 
+import (
+	"fmt"
+	"sort"
+)
+
 // RequirementDefinition as described in Appendix 6.2
 type RequirementDefinition struct {
 	Capability       string `yaml:"capability" json:"capability"`         // The required reserved keyname used that can be used to provide the name of a valid Capability Type that can fulfil the requirement
 	Node             string `yaml:"node,omitempty" json:"node,omitempty"` // The optional reserved keyname used to provide the name of a valid Node Type that contains the capability definition that can be used to fulfil the requirement
 	Relationship     string `yaml:"relationship" json:"relationship,omitempty"`
+	Description      string `yaml:"description,omitempty" json:"description,omitempty"` // The optional description of the requirement definition.
 	RelationshipName string
-	Occurrences      ToscaRange `yaml:"occurences,omitempty" json:"occurences,omitempty"` // The optional minimum and maximum occurrences for the requirement.  Note: the keyword UNBOUNDED is also supported to represent any positive integer
+	Occurrences      ToscaRange `yaml:"occurrences,omitempty" json:"occurrences,omitempty"` // The optional minimum and maximum occurrences for the requirement.  Note: the keyword UNBOUNDED is also supported to represent any positive integer
 }
 
+// defaultOccurrences are the occurrences of a requirement or capability definition that does not declare them
+var defaultOccurrences = ToscaRange{1, 1}
+
 // UnmarshalYAML is used to match both Simple Notation Example and Full Notation Example
 func (r *RequirementDefinition) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	// First try the Short notation
@@ -31,14 +40,16 @@ func (r *RequirementDefinition) UnmarshalYAML(unmarshal func(interface{}) error)
 	err := unmarshal(&cas)
 	if err == nil {
 		r.Capability = cas
+		r.Occurrences = defaultOccurrences
 		return nil
 	}
 	// If error, try the full struct
 	var test2 struct {
-		Capability   string     `yaml:"capability" json:"capability"`         // The required reserved keyname used that can be used to provide the name of a valid Capability Type that can fulfil the requirement
-		Node         string     `yaml:"node,omitempty" json:"node,omitempty"` // The optional reserved keyname used to provide the name of a valid Node Type that contains the capability definition that can be used to fulfil the requirement
-		Relationship string     `yaml:"relationship" json:"relationship,omitempty"`
-		Occurrences  ToscaRange `yaml:"occurences,omitempty" json:"occurences,omitempty"` // The optional minimum and maximum occurrences for the requirement.  Note: the keyword UNBOUNDED is also supported to represent any positive integer
+		Capability   string              `yaml:"capability" json:"capability"`         // The required reserved keyname used that can be used to provide the name of a valid Capability Type that can fulfil the requirement
+		Node         string              `yaml:"node,omitempty" json:"node,omitempty"` // The optional reserved keyname used to provide the name of a valid Node Type that contains the capability definition that can be used to fulfil the requirement
+		Relationship relationshipKeyname `yaml:"relationship" json:"relationship,omitempty"`
+		Description  string              `yaml:"description,omitempty" json:"description,omitempty"`
+		Occurrences  *ToscaRange         `yaml:"occurrences,omitempty" json:"occurrences,omitempty"` // The optional minimum and maximum occurrences for the requirement.  Note: the keyword UNBOUNDED is also supported to represent any positive integer
 	}
 	err = unmarshal(&test2)
 	if err != nil {
@@ -46,8 +57,12 @@ func (r *RequirementDefinition) UnmarshalYAML(unmarshal func(interface{}) error)
 	}
 	r.Capability = test2.Capability
 	r.Node = test2.Node
-	r.Relationship = test2.Relationship
-	r.Occurrences = test2.Occurrences
+	r.Relationship = string(test2.Relationship)
+	r.Description = test2.Description
+	r.Occurrences = defaultOccurrences
+	if test2.Occurrences != nil {
+		r.Occurrences = *test2.Occurrences
+	}
 	return nil
 }
 
@@ -73,6 +88,13 @@ func (r *RequirementAssignment) UnmarshalYAML(unmarshal func(interface{}) error)
 		r.Node = test2.Node
 		r.Nodefilter = test2.Nodefilter
 		r.Relationship = test2.Relationship
+		// The extended notation holds the name of the relationship type in its type keyname
+		var rel struct {
+			Relationship RequirementRelationship `yaml:"relationship,omitempty"`
+		}
+		if err := unmarshal(&rel); err == nil {
+			r.RelationshipName = rel.Relationship.Type
+		}
 		return nil
 	}
 	var test3 struct {
@@ -117,3 +139,185 @@ type RequirementRelationship struct {
 	Properties map[string]interface{}         `yaml:"properties" json:"properties"`                     // The optional list property definitions that comprise the schema for a complex Data Type in TOSCA.
 
 }
+
+// relationshipKeyname is the name of the relationship type of a requirement definition.
+// It is given either as a string or as the type keyname of a relationship definition
+type relationshipKeyname string
+
+func (r *relationshipKeyname) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*r = relationshipKeyname(s)
+		return nil
+	}
+	var rel RequirementRelationship
+	if err := unmarshal(&rel); err != nil {
+		return err
+	}
+	*r = relationshipKeyname(rel.Type)
+	return nil
+}
+
+// RequirementMatch is the capability of a target node template fulfilling a requirement
+type RequirementMatch struct {
+	Requirement    string // The name of the requirement
+	Target         string // The name of the target node template
+	Capability     string // The name of the capability of the target
+	CapabilityType string // The type of the capability of the target
+	Relationship   string // The relationship type used to relate the node to the target
+	Template       string // The name of the relationship template, if the requirement names one
+}
+
+// MatchRequirements matches each requirement assignment of the node template
+// named node with a compatible capability of its target node template.
+// Requirements whose target is a Node Type rather than a Node Template are
+// left to the orchestrator and are not returned.
+func (s *ServiceTemplateDefinition) MatchRequirements(node string) ([]RequirementMatch, error) {
+	nt := s.GetNodeTemplate(node)
+	if nt == nil {
+		return nil, fmt.Errorf("Node template %v not found", node)
+	}
+	sourceType, err := s.FlattenNodeType(nt.Type)
+	if err != nil {
+		return nil, err
+	}
+	var matches []RequirementMatch
+	for _, req := range nt.Requirements {
+		for name, ra := range req {
+			m, ok, err := s.matchRequirement(nt, sourceType, name, ra)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matches = append(matches, m)
+			}
+		}
+	}
+	return matches, nil
+}
+
+// matchRequirement finds the capability fulfilling the requirement assignment ra named name of the node template nt
+func (s *ServiceTemplateDefinition) matchRequirement(nt *NodeTemplate, sourceType NodeType, name string, ra RequirementAssignment) (RequirementMatch, bool, error) {
+	def, _ := sourceType.getRequirement(name)
+	m := RequirementMatch{
+		Requirement:  name,
+		Target:       ra.Node,
+		Relationship: def.Relationship,
+	}
+	if ra.RelationshipName != "" {
+		m.Relationship = ra.RelationshipName
+	}
+	// The relationship may name a relationship template rather than a relationship type
+	if rt, ok := s.TopologyTemplate.RelationshipTemplates[ra.RelationshipName]; ok {
+		m.Template = ra.RelationshipName
+		m.Relationship = rt.Type
+	}
+	target := s.GetNodeTemplate(ra.Node)
+	if target == nil && !ra.Nodefilter.isEmpty() {
+		// Select the target node template with the node filter
+		nodeType := def.Node
+		if _, ok := s.NodeTypes[ra.Node]; ok {
+			nodeType = ra.Node
+		}
+		selected, err := s.filterNode(ra.Nodefilter, nodeType, nt.Name)
+		if err != nil {
+			return m, false, fmt.Errorf("Node %v: requirement %v: %v", nt.Name, name, err)
+		}
+		m.Target = selected
+		target = s.GetNodeTemplate(selected)
+	}
+	if target == nil {
+		if _, ok := s.NodeTypes[ra.Node]; ok || ra.Node == "" {
+			return m, false, nil
+		}
+		return m, false, fmt.Errorf("Node %v: requirement %v: target node %v not found", nt.Name, name, ra.Node)
+	}
+	// The capability of the assignment may either be a capability type or the name of a capability of the target
+	capType := def.Capability
+	if _, ok := s.CapabilityTypes[ra.Capability]; ok {
+		capType = ra.Capability
+	}
+	targetType, err := s.FlattenNodeType(target.Type)
+	if err != nil {
+		return m, false, err
+	}
+	var candidates []string
+	if _, ok := targetType.Capabilities[ra.Capability]; ok {
+		candidates = []string{ra.Capability}
+	} else {
+		for capName := range targetType.Capabilities {
+			candidates = append(candidates, capName)
+		}
+		sort.Strings(candidates)
+	}
+	var validTargets []string
+	if m.Relationship != "" {
+		rt, err := s.RelationshipType(m.Relationship)
+		if err != nil {
+			return m, false, fmt.Errorf("Node %v: requirement %v: %v", nt.Name, name, err)
+		}
+		validTargets = rt.ValidTarget
+	}
+	var forbidden string
+	for _, capName := range candidates {
+		capDef := targetType.Capabilities[capName]
+		if capType != "" && !s.isCapabilityType(capDef.Type, capType) {
+			continue
+		}
+		if !s.isValidSource(nt.Type, capDef) {
+			continue
+		}
+		if !s.isValidTarget(capDef.Type, validTargets) {
+			if forbidden == "" {
+				forbidden = capDef.Type
+			}
+			continue
+		}
+		m.Capability = capName
+		m.CapabilityType = capDef.Type
+		return m, true, nil
+	}
+	if forbidden != "" {
+		relationship := m.Relationship
+		if m.Template != "" {
+			relationship = fmt.Sprintf("%v (%v)", m.Template, m.Relationship)
+		}
+		return m, false, fmt.Errorf("Node %v: requirement %v: relationship %v does not accept capability type %v of %v as target (valid targets: %v)", nt.Name, name, relationship, forbidden, m.Target, validTargets)
+	}
+	return m, false, fmt.Errorf("Node %v: requirement %v: no capability of %v is compatible with %v", nt.Name, name, m.Target, capType)
+}
+
+// isValidTarget returns true if the capability type capType is one of the valid targets or derives from it.
+// An empty list of valid targets accepts any capability type
+func (s *ServiceTemplateDefinition) isValidTarget(capType string, validTargets []string) bool {
+	if len(validTargets) == 0 {
+		return true
+	}
+	for _, target := range validTargets {
+		if s.isCapabilityType(capType, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidSource returns true if the node type nodeType is accepted as a source by the capability capDef
+func (s *ServiceTemplateDefinition) isValidSource(nodeType string, capDef CapabilityDefinition) bool {
+	sources := capDef.ValidSourceTypes
+	if len(sources) == 0 {
+		ct, err := s.CapabilityType(capDef.Type)
+		if err != nil {
+			return true
+		}
+		sources = ct.ValidSources
+	}
+	if len(sources) == 0 {
+		return true
+	}
+	for _, src := range sources {
+		if s.isNodeType(nodeType, src) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"testing"
+)
+
+const requirementsTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+capability_types:
+  my.capabilities.Storage:
+    derived_from: tosca.capabilities.Root
+  my.capabilities.FastStorage:
+    derived_from: my.capabilities.Storage
+node_types:
+  my.nodes.Store:
+    derived_from: tosca.nodes.Root
+    capabilities:
+      store: my.capabilities.Storage
+  my.nodes.FastStore:
+    derived_from: tosca.nodes.Root
+    capabilities:
+      store: my.capabilities.FastStorage
+  my.nodes.App:
+    derived_from: tosca.nodes.Root
+    requirements:
+      - storage:
+          capability: my.capabilities.Storage
+          relationship: tosca.relationships.Root
+topology_template:
+  node_templates:
+    store:
+      type: my.nodes.Store
+    fast:
+      type: my.nodes.FastStore
+    other:
+      type: tosca.nodes.Root
+    app_direct:
+      type: my.nodes.App
+      requirements:
+        - storage: store
+    app_derived:
+      type: my.nodes.App
+      requirements:
+        - storage: fast
+    app_wrong:
+      type: my.nodes.App
+      requirements:
+        - storage: other
+`
+
+func TestMatchRequirements(t *testing.T) {
+	s := parseString(t, requirementsTemplate)
+	m, err := s.MatchRequirements("app_direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].Target != "store" || m[0].Capability != "store" || m[0].CapabilityType != "my.capabilities.Storage" {
+		t.Fatalf("bad match %+v", m)
+	}
+	m, err = s.MatchRequirements("app_derived")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].Target != "fast" || m[0].CapabilityType != "my.capabilities.FastStorage" {
+		t.Fatalf("bad match %+v", m)
+	}
+	if _, err := s.MatchRequirements("app_wrong"); err == nil {
+		t.Fatal("a target without a compatible capability should not match")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+)
+
+// ResolvedNode is the self-contained description of a node template, as returned by ResolveNode
+type ResolvedNode struct {
+	Name         string                            // The name of the node template
+	Type         string                            // The name of its node type
+	Properties   map[string]interface{}            // The values of the properties, defaults included and functions evaluated
+	Capabilities map[string]map[string]interface{} // The values of the properties of each capability, defaults included and functions evaluated
+	Interfaces   map[string]InterfaceDefinition    // The interfaces inherited from the node type merged with the ones of the template
+	Requirements []RequirementMatch                // The capabilities fulfilling the requirements of the template
+}
+
+// ResolveNode returns the node template named name with its properties evaluated against the inputs,
+// the properties of its capabilities evaluated likewise, its interfaces merged (see EffectiveInterfaces)
+// and its requirements matched (see MatchRequirements).
+// The inputs that are not given take the value or the default value of their definition.
+// It fails if a property assigned a function resolves to no value, such as an input that has none.
+func (s *ServiceTemplateDefinition) ResolveNode(name string, inputs map[string]interface{}) (ResolvedNode, error) {
+	nt := s.GetNodeTemplate(name)
+	if nt == nil {
+		return ResolvedNode{}, fmt.Errorf("Node template %v not found", name)
+	}
+	props, err := s.EffectiveProperties(name)
+	if err != nil {
+		return ResolvedNode{}, err
+	}
+	ctx := &Context{Inputs: inputs}
+	res := ResolvedNode{
+		Name:       name,
+		Type:       nt.Type,
+		Properties: make(map[string]interface{}, len(props)),
+	}
+	for prop, pa := range props {
+		if pa.IsNull() {
+			res.Properties[prop] = nil
+			continue
+		}
+		v, err := s.evaluate(PA{PA: pa, Origin: name}, ctx)
+		if err != nil {
+			return ResolvedNode{}, fmt.Errorf("Node %v: property %v: %v", name, prop, err)
+		}
+		if _, literal := pa["value"]; !literal && (v == nil || v == "") {
+			return ResolvedNode{}, fmt.Errorf("Node %v: property %v is unresolved: its input must be given a value", name, prop)
+		}
+		res.Properties[prop] = v
+	}
+	if res.Capabilities, err = s.resolveCapabilities(name, ctx); err != nil {
+		return ResolvedNode{}, err
+	}
+	if res.Interfaces, err = s.effectiveInterfaces(name, ctx); err != nil {
+		return ResolvedNode{}, err
+	}
+	if res.Requirements, err = s.MatchRequirements(name); err != nil {
+		return ResolvedNode{}, err
+	}
+	return res, nil
+}
+
+// resolveCapabilities returns the values of the properties of the capabilities of the node template name,
+// evaluated against ctx. The properties without value nor default are left out.
+func (s *ServiceTemplateDefinition) resolveCapabilities(name string, ctx *Context) (map[string]map[string]interface{}, error) {
+	typ, err := s.FlattenNodeType(s.GetNodeTemplate(name).Type)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]map[string]interface{}, len(typ.Capabilities))
+	for capName, capDef := range typ.Capabilities {
+		props, err := s.capabilityProperties(capDef)
+		if err != nil {
+			return nil, fmt.Errorf("Node %v: capability %v: %v", name, capName, err)
+		}
+		values := make(map[string]interface{}, len(props))
+		for prop := range props {
+			v, err := s.GetCapabilityProperty(name, capName, prop)
+			if err != nil {
+				return nil, err
+			}
+			if pa, ok := v.(PropertyAssignment); ok {
+				if v, err = s.evaluate(PA{PA: pa, Origin: name}, ctx); err != nil {
+					return nil, fmt.Errorf("Node %v: capability %v: property %v: %v", name, capName, prop, err)
+				}
+			}
+			if v == nil || v == "" {
+				continue
+			}
+			values[prop] = v
+		}
+		res[capName] = values
+	}
+	return res, nil
+}
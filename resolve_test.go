@@ -0,0 +1,92 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"strings"
+	"testing"
+)
+
+const resolveTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Server:
+    derived_from: tosca.nodes.Compute
+    properties:
+      hostname:
+        type: string
+      replicas:
+        type: integer
+        default: 1
+topology_template:
+  inputs:
+    hostname:
+      type: string
+    image:
+      type: string
+      default: ubuntu
+  node_templates:
+    server:
+      type: my.nodes.Server
+      properties:
+        hostname: { get_input: hostname }
+      interfaces:
+        Standard:
+          create:
+            implementation: scripts/create.sh
+            inputs:
+              image: { get_input: image }
+      requirements:
+        - local_storage: storage
+    storage:
+      type: tosca.nodes.BlockStorage
+      properties:
+        size: 10 GB
+`
+
+func TestResolveNode(t *testing.T) {
+	s := parseString(t, resolveTemplate)
+	node, err := s.ResolveNode("server", map[string]interface{}{"hostname": "db01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Type != "my.nodes.Server" {
+		t.Errorf("expected the type my.nodes.Server, got %v", node.Type)
+	}
+	if node.Properties["hostname"] != "db01" {
+		t.Errorf("the hostname should come from the input, got %v", node.Properties["hostname"])
+	}
+	if node.Properties["replicas"] != "1" {
+		t.Errorf("the replicas should take their default value, got %v", node.Properties["replicas"])
+	}
+	create := node.Interfaces["Standard"].Operations["create"]
+	if create.Implementation.Primary != "scripts/create.sh" || create.Inputs["image"].Value != "ubuntu" {
+		t.Errorf("the create operation is not resolved: %+v", create)
+	}
+	if len(node.Requirements) != 1 || node.Requirements[0].Target != "storage" || node.Requirements[0].Capability != "attachment" {
+		t.Errorf("the local_storage requirement should be matched with storage, got %+v", node.Requirements)
+	}
+}
+
+func TestResolveNodeMissingInput(t *testing.T) {
+	s := parseString(t, resolveTemplate)
+	_, err := s.ResolveNode("server", nil)
+	if err == nil || !strings.Contains(err.Error(), "property hostname is unresolved") {
+		t.Fatalf("expected the hostname to be unresolved, got %v", err)
+	}
+	if _, err := s.ResolveNode("client", nil); err == nil {
+		t.Fatal("resolving an unknown node should fail")
+	}
+}
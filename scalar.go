@@ -0,0 +1,872 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scalar type as defined in Appendis 2.6.
+// The scalar unit type can be used to define scalar values along with a unit from the list of recognized units
+// Scalar type may be time.Duration, Size or Frequency
+//
+// A Scalar is the string of the scalar as written, such as "1.5 GiB". It used to be a struct
+// with the Value and Unit fields filled in by its unmarshaling: use Parsed to get them.
+type Scalar string
+
+// ScalarKind is the type of a scalar, named after its TOSCA type
+type ScalarKind string
+
+const (
+	KindSize      ScalarKind = "scalar-unit.size"
+	KindFrequency ScalarKind = "scalar-unit.frequency"
+	KindTime      ScalarKind = "scalar-unit.time"
+	KindDuration  ScalarKind = KindTime // The kind of the durations, another name of KindTime
+	KindUnknown   ScalarKind = ""       // The kind of a unit that is not known
+)
+
+// ErrMissingUnit is returned when a scalar is a plain number without any unit
+var ErrMissingUnit = errors.New("Missing unit in TOSCA scalar")
+
+// sizeUnits holds the number of bytes of each scalar-unit.size unit
+var sizeUnits = map[string]float64{
+	"B":   1,
+	"kB":  1000,
+	"KiB": 1024,
+	"MB":  1000000,
+	"MiB": 1048576,
+	"GB":  1000000000,
+	"GiB": 1073741824,
+	"TB":  1000000000000,
+	"TiB": 1099511627776,
+	"PB":  1000000000000000,
+	"PiB": 1125899906842624,
+}
+
+// frequencyUnits holds the number of Hz of each scalar-unit.frequency unit
+var frequencyUnits = map[string]float64{
+	"Hz":  1,
+	"kHz": 1000,
+	"MHz": 1000000,
+	"GHz": 1000000000,
+	"THz": 1000000000000,
+}
+
+// durationUnits holds the duration of each scalar-unit.time unit
+var durationUnits = map[string]time.Duration{
+	"d":  24 * time.Hour,
+	"h":  time.Hour,
+	"m":  time.Minute,
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// The regular expressions used to classify a scalar, compiled once.
+// They are anchored at both ends so that a unit only matches as a whole: Hz is never found within kHz.
+var (
+	isSize        = regexp.MustCompile("^([0-9.]+)[[:blank:]]*(B|kB|KiB|MB|MiB|GB|GiB|TB|TiB|PB|PiB)$")
+	isFrequency   = regexp.MustCompile("^([0-9.]+)[[:blank:]]*(Hz|kHz|MHz|GHz|THz)$")
+	isDuration    = regexp.MustCompile("^([0-9.]+)[[:blank:]]*(d|h|m|s|ms|us|ns)$")
+	isNumber      = regexp.MustCompile("^[0-9.]+$")
+	hasUnit       = regexp.MustCompile("^[0-9.]+[[:blank:]]*([[:alpha:]]+)$")
+	scalarParts   = regexp.MustCompile("^([0-9.]*)[[:space:]]*([[:alpha:]]+)$")
+	decimalComma  = regexp.MustCompile("^([0-9]+),([0-9]+)")
+	uppercaseKilo = regexp.MustCompile("^([0-9.,]+[[:blank:]]*)KB$")
+)
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface
+// Unmarshals a string of the form "scalar unit" into a Scalar, validating that scalar and unit are valid.
+// A bare number zero is accepted as the zero of any kind and kept as "0": the kind it is evaluated in
+// is the one declared by its property. Any other number without unit is rejected.
+func (s *Scalar) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var sString string
+	err := unmarshal(&sString)
+	if err != nil {
+		return err
+	}
+	_, err = Scalar(sString).Evaluate()
+	if err == ErrMissingUnit {
+		if _, zero := zeroScalar(sString, KindUnknown); zero {
+			*s = "0"
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	*s = Scalar(sString)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler. It fails if the scalar is neither valid nor empty.
+func (s Scalar) MarshalText() ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if _, err := s.Evaluate(); err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Like UnmarshalYAML, it rejects the invalid scalars;
+// an empty text is the empty scalar.
+func (s *Scalar) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = ""
+		return nil
+	}
+	if _, err := Scalar(text).Evaluate(); err != nil {
+		if _, zero := zeroScalar(string(text), KindUnknown); err == ErrMissingUnit && zero {
+			*s = "0"
+			return nil
+		}
+		return err
+	}
+	*s = Scalar(text)
+	return nil
+}
+
+// Evaluate returns the value of the scalar expressed in the base unit of its type:
+// a Size in bytes, a Frequency in Hz or a time.Duration.
+// It returns ErrMissingUnit if the scalar is a number without unit.
+func (s Scalar) Evaluate() (interface{}, error) {
+	str := strings.TrimSpace(string(s))
+	if isNumber.MatchString(str) {
+		return nil, ErrMissingUnit
+	}
+	if res := isSize.FindStringSubmatch(str); len(res) == 3 {
+		val, err := scaleValue(res[1], sizeUnits[res[2]])
+		return Size(val), err
+	}
+	if res := isFrequency.FindStringSubmatch(str); len(res) == 3 {
+		val, err := scaleValue(res[1], frequencyUnits[res[2]])
+		return Frequency(val), err
+	}
+	if res := isDuration.FindStringSubmatch(str); len(res) == 3 {
+		val, err := scaleValue(res[1], float64(durationUnits[res[2]]))
+		return time.Duration(val), err
+	}
+	if res := hasUnit.FindStringSubmatch(str); len(res) == 2 {
+		return nil, fmt.Errorf("Unknown unit %v in TOSCA scalar %v", res[1], str)
+	}
+	return nil, fmt.Errorf("Not a TOSCA scalar")
+}
+
+// SplitScalar separates the number and the unit of the scalar s: "  1.5   GiB " is split into "1.5" and "GiB".
+// The blanks around and between the parts are ignored and may be omitted, as in "1.5GiB".
+// It returns ErrMissingUnit if s is a number without unit; the unit is not checked against the known units.
+func SplitScalar(s string) (value, unit string, err error) {
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return "", "", fmt.Errorf("Empty TOSCA scalar")
+	}
+	if isNumber.MatchString(str) {
+		return "", "", ErrMissingUnit
+	}
+	res := scalarParts.FindStringSubmatch(str)
+	if len(res) != 3 {
+		return "", "", fmt.Errorf("Not a TOSCA scalar %v", str)
+	}
+	if res[1] == "" {
+		return "", "", fmt.Errorf("Missing value in TOSCA scalar %v", str)
+	}
+	return res[1], res[2], nil
+}
+
+// UnitKind returns the kind of the scalars written with unit, such as KindSize for "GiB",
+// or KindUnknown if unit is not a TOSCA unit. Units are case-sensitive: "mHz" is unknown.
+func UnitKind(unit string) ScalarKind {
+	switch {
+	case sizeUnits[unit] != 0:
+		return KindSize
+	case frequencyUnits[unit] != 0:
+		return KindFrequency
+	case durationUnits[unit] != 0:
+		return KindDuration
+	}
+	return KindUnknown
+}
+
+// Parsed returns the number and the unit of the scalar as written, without converting them
+// to the base unit of its type: "1.5 GiB" is parsed as 1.5, "GiB" and KindSize.
+func (s Scalar) Parsed() (value float64, unit string, kind ScalarKind, err error) {
+	number, unit, err := SplitScalar(string(s))
+	if err != nil {
+		return 0, "", "", err
+	}
+	kind = UnitKind(unit)
+	if kind == KindUnknown {
+		return 0, "", "", fmt.Errorf("Unknown unit %v in TOSCA scalar %v", unit, strings.TrimSpace(string(s)))
+	}
+	value, err = strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("Invalid number %v in TOSCA scalar %v", number, strings.TrimSpace(string(s)))
+	}
+	return value, unit, kind, nil
+}
+
+// Kind returns the kind of the scalar given by its unit, such as KindSize for "1.5 GiB".
+// It fails if the scalar is not valid.
+func (s Scalar) Kind() (ScalarKind, error) {
+	_, kind, err := s.normalize()
+	return kind, err
+}
+
+// checkKind returns an error if the value v is not a valid scalar of the kind.
+// A number zero without unit is accepted as the zero of the kind.
+func checkKind(v interface{}, kind ScalarKind) error {
+	if _, ok := zeroScalar(v, kind); ok {
+		return nil
+	}
+	k, err := Scalar(fmt.Sprint(v)).Kind()
+	if err != nil {
+		return err
+	}
+	if k != kind {
+		return fmt.Errorf("%v is a %v, not a %v", v, k, kind)
+	}
+	return nil
+}
+
+// Value returns the number of the scalar as written, without its unit and without conversion:
+// "1.5 GB" has the value 1.5.
+func (s Scalar) Value() (float64, error) {
+	value, _, _, err := s.Parsed()
+	return value, err
+}
+
+// ScalarOptions are the options of the evaluation of a scalar
+type ScalarOptions struct {
+	// DecimalComma accepts a comma as the decimal separator, as in "1,5 GB".
+	// It is off by default since a comma may also be read as a thousands separator.
+	DecimalComma bool
+	// UppercaseKilo accepts KB as an alias of kB, 1000 bytes, as emitted by some tools.
+	// It is off by default since KB is ambiguous: it is also commonly used for KiB, 1024 bytes.
+	UppercaseKilo bool
+}
+
+// EvaluateWithOptions is like Evaluate with the tolerances enabled by opts
+func (s Scalar) EvaluateWithOptions(opts ScalarOptions) (interface{}, error) {
+	if opts.DecimalComma {
+		s = Scalar(decimalComma.ReplaceAllString(strings.TrimSpace(string(s)), "$1.$2"))
+	}
+	if opts.UppercaseKilo {
+		s = Scalar(uppercaseKilo.ReplaceAllString(strings.TrimSpace(string(s)), "${1}kB"))
+	}
+	return s.Evaluate()
+}
+
+// ParseScalars validates each of values as a scalar.
+// The returned slices are parallel to values: an invalid value has an empty scalar and its error,
+// a valid value has a nil error. The error slice is nil if all the values are valid.
+func ParseScalars(values []string) ([]Scalar, []error) {
+	scalars := make([]Scalar, len(values))
+	var errs []error
+	for i, v := range values {
+		if _, err := Scalar(v).Evaluate(); err != nil {
+			if errs == nil {
+				errs = make([]error, len(values))
+			}
+			errs[i] = fmt.Errorf("%q: %v", v, err)
+			continue
+		}
+		scalars[i] = Scalar(v)
+	}
+	return scalars, errs
+}
+
+// MustEvaluate is like Evaluate but panics if the scalar cannot be evaluated.
+// It simplifies safe initialization of values from scalar constants and must
+// not be used on scalars read from a template.
+func (s Scalar) MustEvaluate() interface{} {
+	v, err := s.Evaluate()
+	if err != nil {
+		panic(`toscalib: Scalar(` + strconv.Quote(string(s)) + `).MustEvaluate(): ` + err.Error())
+	}
+	return v
+}
+
+// Format returns the scalar expressed in unit with prec decimals, such as "1.5 GiB".
+// It fails if unit does not belong to the same type as the scalar.
+func (s Scalar) Format(unit string, prec int) (string, error) {
+	value, err := s.in(unit)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(value, 'f', prec, 64) + " " + unit, nil
+}
+
+// RoundTo returns the scalar rounded to the nearest whole number of unit, halves rounded away
+// from zero: "1600 MiB" is rounded to "2 GiB".
+// It fails if unit does not belong to the same type as the scalar.
+func (s Scalar) RoundTo(unit string) (Scalar, error) {
+	value, err := s.in(unit)
+	if err != nil {
+		return "", err
+	}
+	return Scalar(strconv.FormatFloat(math.Round(value), 'f', -1, 64) + " " + unit), nil
+}
+
+// in returns the value of the scalar expressed in unit
+func (s Scalar) in(unit string) (float64, error) {
+	v, err := s.Evaluate()
+	if err != nil {
+		return 0, err
+	}
+	var value, factor float64
+	var ok bool
+	switch v := v.(type) {
+	case Size:
+		value = float64(v)
+		factor, ok = sizeUnits[unit]
+	case Frequency:
+		value = float64(v)
+		factor, ok = frequencyUnits[unit]
+	case time.Duration:
+		var d time.Duration
+		value = float64(v)
+		d, ok = durationUnits[unit]
+		factor = float64(d)
+	}
+	if !ok {
+		return 0, fmt.Errorf("Cannot express %v in %v", s, unit)
+	}
+	return value / factor, nil
+}
+
+// normalize returns the value of the scalar in the base unit of its type and the type
+func (s Scalar) normalize() (float64, ScalarKind, error) {
+	v, err := s.Evaluate()
+	if err != nil {
+		return 0, "", err
+	}
+	switch v := v.(type) {
+	case Size:
+		return float64(v), KindSize, nil
+	case Frequency:
+		return float64(v), KindFrequency, nil
+	case time.Duration:
+		return float64(v), KindTime, nil
+	}
+	return 0, "", fmt.Errorf("Not a TOSCA scalar")
+}
+
+// NormalizeInt returns the value of the scalar in the base unit of its type as an exact integer, along with
+// that unit: "8 PiB" is 9007199254740992 "B". Unlike normalize, which computes with float64 values,
+// no precision is lost for large values. A fractional remainder is truncated, as by Evaluate.
+// It fails if the value exceeds the range of uint64.
+func (s Scalar) NormalizeInt() (uint64, string, error) {
+	_, unit, kind, err := s.Parsed()
+	if err != nil {
+		return 0, "", err
+	}
+	number, _, _ := SplitScalar(string(s))
+	var factor int64
+	switch kind {
+	case KindSize:
+		factor = int64(sizeUnits[unit])
+	case KindFrequency:
+		factor = int64(frequencyUnits[unit])
+	case KindTime:
+		factor = int64(durationUnits[unit])
+	}
+	value, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return 0, "", fmt.Errorf("Invalid number %v in TOSCA scalar %v", number, strings.TrimSpace(string(s)))
+	}
+	value.Mul(value, new(big.Rat).SetInt64(factor))
+	n := new(big.Int).Quo(value.Num(), value.Denom())
+	if !n.IsUint64() {
+		return 0, "", fmt.Errorf("Scalar %v out of range", strings.TrimSpace(string(s)))
+	}
+	return n.Uint64(), baseUnits[kind], nil
+}
+
+// baseUnits holds the unit in which each scalar type is normalized
+var baseUnits = map[ScalarKind]string{
+	KindSize:      "B",
+	KindFrequency: "Hz",
+	KindTime:      "ns",
+}
+
+// zeroScalar returns the zero of the kind in its base unit if v is the number zero written without unit,
+// as in `disk_size: 0`. Any other number still misses its unit.
+func zeroScalar(v interface{}, kind ScalarKind) (Scalar, bool) {
+	str := strings.TrimSpace(fmt.Sprint(v))
+	if !isNumber.MatchString(str) {
+		return "", false
+	}
+	if f, err := strconv.ParseFloat(str, 64); err != nil || f != 0 {
+		return "", false
+	}
+	return Scalar("0 " + baseUnits[kind]), true
+}
+
+// NormalizeScalars rewrites the scalar-unit properties assigned by the node templates
+// in the base unit of their type (B, Hz or ns), "1 KiB" becomes "1024 B".
+// The properties whose value is a function are left untouched and reported in the error.
+func (s *ServiceTemplateDefinition) NormalizeScalars() error {
+	var unresolved []string
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		nt, err := s.FlattenNodeType(node.Type)
+		if err != nil {
+			return fmt.Errorf("Node %v: %v", name, err)
+		}
+		for prop, pa := range node.Properties {
+			if _, ok := baseUnits[ScalarKind(nt.Properties[prop].Type)]; !ok || pa.IsNull() {
+				continue
+			}
+			v, ok := pa["value"]
+			if !ok {
+				unresolved = append(unresolved, name+"."+prop)
+				continue
+			}
+			if len(v) != 1 {
+				return fmt.Errorf("Node %v: property %v: not a scalar %v", name, prop, v)
+			}
+			sc := Scalar(fmt.Sprint(v[0]))
+			if zero, ok := zeroScalar(v[0], ScalarKind(nt.Properties[prop].Type)); ok {
+				sc = zero
+			}
+			canonical, err := sc.CanonicalKey()
+			if err != nil {
+				return fmt.Errorf("Node %v: property %v: %v", name, prop, err)
+			}
+			node.Properties[prop] = PropertyAssignment{"value": []interface{}{canonical}}
+		}
+	}
+	if len(unresolved) != 0 {
+		sort.Strings(unresolved)
+		return fmt.Errorf("Cannot normalize the scalar properties holding a function: %v", strings.Join(unresolved, ", "))
+	}
+	return nil
+}
+
+// CanonicalKey returns the scalar expressed in the base unit of its type, such as "1024 B" for "1 KiB".
+// Equivalent scalars have the same canonical key.
+func (s Scalar) CanonicalKey() (string, error) {
+	val, kind, err := s.normalize()
+	if err != nil {
+		return "", err
+	}
+	canonical, err := fromBase(val, kind)
+	return string(canonical), err
+}
+
+// Add returns the sum of s and other in the base unit of their type.
+// It fails if they are not of the same type.
+func (s Scalar) Add(other Scalar) (Scalar, error) {
+	a, b, kind, err := s.operands(other)
+	if err != nil {
+		return "", err
+	}
+	return fromBase(a+b, kind)
+}
+
+// Sub returns the difference of s and other in the base unit of their type.
+// It fails if they are not of the same type or if the result is negative.
+func (s Scalar) Sub(other Scalar) (Scalar, error) {
+	a, b, kind, err := s.operands(other)
+	if err != nil {
+		return "", err
+	}
+	if a < b {
+		return "", fmt.Errorf("Cannot subtract %v from %v: negative result", other, s)
+	}
+	return fromBase(a-b, kind)
+}
+
+// Mul returns s multiplied by factor in the base unit of its type.
+// The factor must be non-negative: a factor of 0 returns the zero of the type, such as "0 B".
+func (s Scalar) Mul(factor float64) (Scalar, error) {
+	val, kind, err := s.normalize()
+	if err != nil {
+		return "", err
+	}
+	if factor < 0 || math.IsNaN(factor) {
+		return "", fmt.Errorf("Cannot multiply %v by %v", s, factor)
+	}
+	return fromBase(val*factor, kind)
+}
+
+// DivideBy returns the ratio of s to other, such as 0.5 for "512 MiB" divided by "1 GiB".
+// It fails if they are not of the same type or if other is zero.
+func (s Scalar) DivideBy(other Scalar) (float64, error) {
+	a, b, _, err := s.operands(other)
+	if err != nil {
+		return 0, err
+	}
+	if b == 0 {
+		return 0, fmt.Errorf("Cannot divide %v by %v: division by zero", s, other)
+	}
+	return a / b, nil
+}
+
+// Clamp returns min if the scalar is below min, max if it is above max, otherwise the scalar itself.
+// The three scalars must be of the same type and min must not be greater than max.
+func (s Scalar) Clamp(min, max Scalar) (Scalar, error) {
+	v, lo, _, err := s.operands(min)
+	if err != nil {
+		return "", err
+	}
+	_, hi, _, err := s.operands(max)
+	if err != nil {
+		return "", err
+	}
+	if lo > hi {
+		return "", fmt.Errorf("Cannot clamp %v to [%v, %v]: empty range", s, min, max)
+	}
+	switch {
+	case v < lo:
+		return min, nil
+	case v > hi:
+		return max, nil
+	}
+	return s, nil
+}
+
+// EqualDuration returns true if the scalar is the duration d. It fails if the scalar is not a scalar-unit.time.
+func (s Scalar) EqualDuration(d time.Duration) (bool, error) {
+	v, err := s.Evaluate()
+	if err != nil {
+		return false, err
+	}
+	duration, ok := v.(time.Duration)
+	if !ok {
+		return false, fmt.Errorf("Cannot compare %v with the duration %v", s, d)
+	}
+	return duration == d, nil
+}
+
+// EqualBytes returns true if the scalar is the size of b bytes. It fails if the scalar is not a scalar-unit.size.
+func (s Scalar) EqualBytes(b uint64) (bool, error) {
+	v, err := s.Evaluate()
+	if err != nil {
+		return false, err
+	}
+	size, ok := v.(Size)
+	if !ok {
+		return false, fmt.Errorf("Cannot compare %v with the size of %v bytes", s, b)
+	}
+	return size >= 0 && uint64(size) == b, nil
+}
+
+// operands returns the normalized values of s and other, which must be of the same type
+func (s Scalar) operands(other Scalar) (float64, float64, ScalarKind, error) {
+	a, ka, err := s.normalize()
+	if err != nil {
+		return 0, 0, "", err
+	}
+	b, kb, err := other.normalize()
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if ka != kb {
+		return 0, 0, "", fmt.Errorf("Cannot combine %v (%v) with %v (%v)", s, ka, other, kb)
+	}
+	return a, b, ka, nil
+}
+
+// fromBase returns the scalar of the type kind whose value in the base unit is val
+func fromBase(val float64, kind ScalarKind) (Scalar, error) {
+	if math.IsInf(val, 0) || val >= math.MaxInt64 {
+		return "", fmt.Errorf("Scalar out of range")
+	}
+	return Scalar(strconv.FormatFloat(val, 'f', -1, 64) + " " + baseUnits[kind]), nil
+}
+
+// MinScalar returns the smallest of scalars.
+// All the scalars must be of the same type.
+func MinScalar(scalars ...Scalar) (Scalar, error) {
+	return extremeScalar(scalars, func(a, b float64) bool { return a < b })
+}
+
+// MaxScalar returns the largest of scalars.
+// All the scalars must be of the same type.
+func MaxScalar(scalars ...Scalar) (Scalar, error) {
+	return extremeScalar(scalars, func(a, b float64) bool { return a > b })
+}
+
+// NormalizeSizes returns the values in bytes of scalars, which must all be sizes
+func NormalizeSizes(scalars []Scalar) ([]float64, error) {
+	return NormalizeKind(KindSize, scalars)
+}
+
+// NormalizeKind returns the values of scalars in the base unit of kind.
+// It fails if one of the scalars is invalid or of another kind.
+func NormalizeKind(kind ScalarKind, scalars []Scalar) ([]float64, error) {
+	values := make([]float64, len(scalars))
+	for i, s := range scalars {
+		v, k, err := s.normalize()
+		if err != nil {
+			return nil, fmt.Errorf("Scalar %v at index %v: %v", s, i, err)
+		}
+		if k != kind {
+			return nil, fmt.Errorf("Scalar %v at index %v is a %v, not a %v", s, i, k, kind)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// SumSizes returns the total in bytes of scalars, which must all be sizes.
+// It fails rather than wrapping around if the total overflows a Size.
+func SumSizes(scalars []Scalar) (Size, error) {
+	var total Size
+	for i, s := range scalars {
+		v, err := s.Evaluate()
+		if err != nil {
+			return 0, fmt.Errorf("Scalar %v at index %v: %v", s, i, err)
+		}
+		size, ok := v.(Size)
+		if !ok {
+			kind, _ := s.Kind()
+			return 0, fmt.Errorf("Scalar %v at index %v is a %v, not a %v", s, i, kind, KindSize)
+		}
+		if (size > 0 && total > math.MaxInt64-size) || (size < 0 && total < math.MinInt64-size) {
+			return 0, fmt.Errorf("Scalar %v at index %v: the sum of the sizes overflows", s, i)
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// MaxScalarRangeLength is the largest number of scalars that ScalarSizeRange generates
+const MaxScalarRangeLength = 10000
+
+// ScalarSizeRange returns the sizes from start to end included, increasing by step:
+// "1 GiB" to "4 GiB" by "1 GiB" gives "1 GiB", "2 GiB", "3 GiB" and "4 GiB".
+// The sizes are expressed in the unit of start. The result is empty if start is greater than end.
+// The three scalars must be sizes, step must be positive and the result must not hold
+// more than MaxScalarRangeLength sizes.
+func ScalarSizeRange(start, end, step Scalar) ([]Scalar, error) {
+	values, err := NormalizeSizes([]Scalar{start, end, step})
+	if err != nil {
+		return nil, err
+	}
+	from, to, by := values[0], values[1], values[2]
+	if by <= 0 {
+		return nil, fmt.Errorf("Cannot iterate from %v to %v by %v: the step must be positive", start, end, step)
+	}
+	if from > to {
+		return []Scalar{}, nil
+	}
+	count := math.Floor((to-from)/by) + 1
+	if count > MaxScalarRangeLength {
+		return nil, fmt.Errorf("Cannot iterate from %v to %v by %v: more than %v sizes", start, end, step, MaxScalarRangeLength)
+	}
+	_, unit, _, err := start.Parsed()
+	if err != nil {
+		unit = baseUnits[KindSize]
+	}
+	factor := sizeUnits[unit]
+	res := make([]Scalar, int(count))
+	for i := range res {
+		v := (from + float64(i)*by) / factor
+		res[i] = Scalar(strconv.FormatFloat(v, 'f', -1, 64) + " " + unit)
+	}
+	return res, nil
+}
+
+// SortScalars sorts scalars in place by increasing value; the order of equal values is kept.
+// All the scalars must be of the same type, otherwise scalars is left untouched and an error is returned.
+func SortScalars(scalars []Scalar) error {
+	values := make([]float64, len(scalars))
+	var kind ScalarKind
+	for i, s := range scalars {
+		v, k, err := s.normalize()
+		if err != nil {
+			return err
+		}
+		if i > 0 && k != kind {
+			return fmt.Errorf("Cannot compare %v (%v) with %v (%v)", scalars[0], kind, s, k)
+		}
+		values[i] = v
+		kind = k
+	}
+	sort.Stable(scalarSorter{scalars, values})
+	return nil
+}
+
+// scalarSorter sorts scalars by their normalized values
+type scalarSorter struct {
+	scalars []Scalar
+	values  []float64
+}
+
+func (s scalarSorter) Len() int           { return len(s.scalars) }
+func (s scalarSorter) Less(i, j int) bool { return s.values[i] < s.values[j] }
+func (s scalarSorter) Swap(i, j int) {
+	s.scalars[i], s.scalars[j] = s.scalars[j], s.scalars[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
+
+// extremeScalar returns the scalar whose value is preferred over all the others by better
+func extremeScalar(scalars []Scalar, better func(a, b float64) bool) (Scalar, error) {
+	if len(scalars) == 0 {
+		return "", fmt.Errorf("No scalar to compare")
+	}
+	best, kind, err := scalars[0].normalize()
+	if err != nil {
+		return "", err
+	}
+	res := scalars[0]
+	for _, s := range scalars[1:] {
+		v, k, err := s.normalize()
+		if err != nil {
+			return "", err
+		}
+		if k != kind {
+			return "", fmt.Errorf("Cannot compare %v (%v) with %v (%v)", res, kind, s, k)
+		}
+		if better(v, best) {
+			best = v
+			res = s
+		}
+	}
+	return res, nil
+}
+
+// scaleValue parses the number value and multiplies it by factor.
+// It fails if the result does not fit in an int64
+func scaleValue(value string, factor float64) (int64, error) {
+	val, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Not a number %v", value)
+	}
+	val = val * factor
+	if math.IsInf(val, 0) || math.IsNaN(val) || val >= math.MaxInt64 {
+		return 0, fmt.Errorf("Scalar %v out of range", value)
+	}
+	return int64(val), nil
+}
+
+// DecodeScalarFields evaluates the scalars held by the struct pointed to by v, such as a configuration read from YAML.
+// The fields tagged `tosca:"scalar"` are of type interface{}: their string value is replaced by
+// its evaluation, a Size, a Frequency or a time.Duration.
+// A field of type Size, Frequency or time.Duration cannot hold the string "512 MiB" read from YAML:
+// its tag names the string field holding the scalar, as in `tosca:"scalar,from=MemoryText"`,
+// and the field is set to its evaluation. An empty source leaves the field untouched.
+// The nested structs are decoded as well.
+func DecodeScalarFields(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Cannot decode the scalars of %T: expected a pointer to a struct", v)
+	}
+	return decodeScalarFields(rv.Elem())
+}
+
+// scalarTypes are the types of the fields a scalar is evaluated into
+var scalarTypes = map[reflect.Type]bool{
+	reflect.TypeOf(Size(0)):          true,
+	reflect.TypeOf(Frequency(0)):     true,
+	reflect.TypeOf(time.Duration(0)): true,
+}
+
+// decodeScalarFields evaluates the scalar fields of the struct rv
+func decodeScalarFields(rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f, fv := rt.Field(i), rv.Field(i)
+		if f.PkgPath != "" {
+			// Unexported field
+			continue
+		}
+		opts := strings.Split(f.Tag.Get("tosca"), ",")
+		if opts[0] != "scalar" {
+			if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := decodeScalarFields(fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if scalarTypes[f.Type] {
+			if err := decodeTypedScalar(rv, f, opts[1:]); err != nil {
+				return fmt.Errorf("Field %v: %v", f.Name, err)
+			}
+			continue
+		}
+		if fv.Kind() != reflect.Interface {
+			return fmt.Errorf("Field %v: a scalar field must be of type interface{}, Size, Frequency or time.Duration, not %v", f.Name, f.Type)
+		}
+		var s Scalar
+		switch val := fv.Interface().(type) {
+		case nil, Size, Frequency, time.Duration:
+			// Nothing to decode
+			continue
+		case string:
+			s = Scalar(val)
+		case Scalar:
+			s = val
+		default:
+			return fmt.Errorf("Field %v: %v is not a TOSCA scalar", f.Name, val)
+		}
+		sv, err := s.Evaluate()
+		if err != nil {
+			return fmt.Errorf("Field %v: %v", f.Name, err)
+		}
+		fv.Set(reflect.ValueOf(sv))
+	}
+	return nil
+}
+
+// decodeTypedScalar sets the field f of the struct rv, a Size, a Frequency or a time.Duration,
+// to the evaluation of the scalar held by the string field named by the from option of opts
+func decodeTypedScalar(rv reflect.Value, f reflect.StructField, opts []string) error {
+	var from string
+	for _, opt := range opts {
+		if strings.HasPrefix(opt, "from=") {
+			from = strings.TrimPrefix(opt, "from=")
+		}
+	}
+	if from == "" {
+		return fmt.Errorf("a scalar field of type %v needs the field holding its scalar, as in tosca:\"scalar,from=Field\"", f.Type)
+	}
+	src := rv.FieldByName(from)
+	if !src.IsValid() || src.Kind() != reflect.String {
+		return fmt.Errorf("the source %v is not a string field", from)
+	}
+	if src.String() == "" {
+		return nil
+	}
+	sv, err := Scalar(src.String()).Evaluate()
+	if err != nil {
+		return err
+	}
+	val := reflect.ValueOf(sv)
+	if val.Type() != f.Type {
+		return fmt.Errorf("%v is a %v, not a %v", src.String(), val.Type(), f.Type)
+	}
+	rv.FieldByIndex(f.Index).Set(val)
+	return nil
+}
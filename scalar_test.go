@@ -0,0 +1,838 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScalarEvaluate(t *testing.T) {
+	tests := map[Scalar]interface{}{
+		"1 B":      Size(1),
+		"2 kB":     Size(2000),
+		"1.5 GiB":  Size(1610612736),
+		"10 MB":    Size(10000000),
+		"1GiB":     Size(1073741824),
+		"1 PiB":    Size(1125899906842624),
+		"2 PB":     Size(2000000000000000),
+		"3PB":      Size(3000000000000000),
+		"2 kHz":    Frequency(2000),
+		"1.2 GHz":  Frequency(1200000000),
+		"3 d":      72 * time.Hour,
+		"30 s":     30 * time.Second,
+		"500 ms":   500 * time.Millisecond,
+		"  10 m  ": 10 * time.Minute,
+	}
+	for s, expected := range tests {
+		v, err := s.Evaluate()
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if v != expected {
+			t.Fatalf("%q evaluated to %v (%T), expected %v (%T)", s, v, v, expected, expected)
+		}
+	}
+	for _, s := range []Scalar{"", "GB", "1 2 GB", "1 XB", "1.2.3 GB", "-1 GB"} {
+		if _, err := s.Evaluate(); err == nil {
+			t.Fatalf("%q should not be a valid scalar", s)
+		}
+	}
+}
+
+func TestScalarMissingUnit(t *testing.T) {
+	_, err := Scalar("42").Evaluate()
+	if err != ErrMissingUnit {
+		t.Fatalf("expected ErrMissingUnit, got %v", err)
+	}
+	_, err = Scalar("42 parsecs").Evaluate()
+	if err == nil || err == ErrMissingUnit {
+		t.Fatalf("an unknown unit is not a missing unit, got %v", err)
+	}
+}
+
+func TestScalarFrequencyUnits(t *testing.T) {
+	v, err := Scalar("2 THz").Evaluate()
+	if err != nil || v != Frequency(2000000000000) {
+		t.Fatalf("2 THz: expected 2000000000000 Hz, got %v (%v)", v, err)
+	}
+	for s, expected := range map[Scalar]Frequency{
+		"5 Hz":  5,
+		"2 kHz": 2000,
+		"2kHz":  2000,
+		"3 MHz": 3000000,
+		"1 GHz": 1000000000,
+		"1GHz":  1000000000,
+	} {
+		v, err := s.Evaluate()
+		if err != nil || v != expected {
+			t.Errorf("%q: expected %v Hz, got %v (%v)", s, int64(expected), v, err)
+		}
+		value, unit, _, err := s.Parsed()
+		if err != nil || value*frequencyUnits[unit] != float64(expected) {
+			t.Errorf("%q: the unit %v was not parsed whole (%v)", s, unit, err)
+		}
+	}
+	for _, s := range []Scalar{"10 mHz", "10mHz", "10 hz", "10 MHzz"} {
+		if _, err := s.Evaluate(); err == nil {
+			t.Errorf("%q should not be a valid frequency", s)
+		}
+	}
+}
+
+func TestScalarUnmarshalYAML(t *testing.T) {
+	var v struct {
+		Size Scalar `yaml:"size"`
+	}
+	if err := yaml.Unmarshal([]byte("size: 4096 MB"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Size != "4096 MB" {
+		t.Fatalf("bad scalar %q", v.Size)
+	}
+	if err := yaml.Unmarshal([]byte("size: 4096"), &v); err == nil {
+		t.Fatal("a number without unit should not unmarshal into a scalar")
+	}
+	if err := yaml.Unmarshal([]byte("size: 0"), &v); err != nil || v.Size != "0" {
+		t.Fatalf("a bare zero should unmarshal into the zero scalar, got %q (%v)", v.Size, err)
+	}
+	if err := yaml.Unmarshal([]byte("size: 5"), &v); err != ErrMissingUnit {
+		t.Fatalf("a bare 5 should be rejected with ErrMissingUnit, got %v", err)
+	}
+}
+
+func TestScalarUnsupportedTimeUnits(t *testing.T) {
+	for _, in := range []string{"2 w", "1 y", "3 mo"} {
+		_, err := Scalar(in).Evaluate()
+		if err == nil {
+			t.Errorf("%q should not evaluate", in)
+			continue
+		}
+		unit := strings.Fields(in)[1]
+		if !strings.Contains(err.Error(), "Unknown unit "+unit) {
+			t.Errorf("%q: the error should name the unit %v, got %v", in, unit, err)
+		}
+		var v struct {
+			Timeout Scalar `yaml:"timeout"`
+		}
+		if err := yaml.Unmarshal([]byte("timeout: "+in), &v); err == nil {
+			t.Errorf("%q should not unmarshal into a scalar", in)
+		}
+	}
+}
+
+func TestScalarEvaluateOutOfRange(t *testing.T) {
+	for _, s := range []Scalar{
+		"9223372036854775807 TiB",
+		"99999999999999999999 ns",
+		"1" + Scalar(strings.Repeat("0", 400)) + " B",
+	} {
+		if _, err := s.Evaluate(); err == nil {
+			t.Fatalf("%q should be out of range", s[:20])
+		}
+	}
+}
+
+func FuzzScalarEvaluate(f *testing.F) {
+	for _, seed := range []string{
+		"1 GiB",
+		"1.5 GHz",
+		"10 ms",
+		"42",
+		"",
+		" ",
+		"...",
+		"1.2.3 MB",
+		"1\x00 GB",
+		"1 G\x00B",
+		"NaN B",
+		"Inf Hz",
+		"1e308 B",
+		"9223372036854775807 TiB",
+		"1" + strings.Repeat("0", 400) + " B",
+		"\xff\xfe 1 B",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		v, err := Scalar(in).Evaluate()
+		if err != nil {
+			return
+		}
+		switch val := v.(type) {
+		case Size:
+			if val < 0 {
+				t.Fatalf("%q evaluated to a negative size %v", in, val)
+			}
+		case Frequency:
+			if val < 0 {
+				t.Fatalf("%q evaluated to a negative frequency %v", in, val)
+			}
+		case time.Duration:
+			if val < 0 {
+				t.Fatalf("%q evaluated to a negative duration %v", in, val)
+			}
+		default:
+			t.Fatalf("%q evaluated to an unexpected type %T", in, v)
+		}
+	})
+}
+
+func BenchmarkScalarEvaluate(b *testing.B) {
+	scalars := []Scalar{"1.5 GiB", "2 GHz", "500 ms", "42"}
+	for i := 0; i < b.N; i++ {
+		for _, s := range scalars {
+			s.Evaluate()
+		}
+	}
+}
+
+func TestScalarMustEvaluate(t *testing.T) {
+	if v := Scalar("2 KiB").MustEvaluate(); v != Size(2048) {
+		t.Fatalf("expected 2048, got %v", v)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustEvaluate should panic on an invalid scalar")
+		}
+	}()
+	Scalar("2 parsecs").MustEvaluate()
+}
+
+func TestScalarFormat(t *testing.T) {
+	tests := []struct {
+		in   Scalar
+		unit string
+		prec int
+		out  string
+	}{
+		{"1610612736 B", "GiB", 1, "1.5 GiB"},
+		{"1610612736 B", "GiB", 0, "2 GiB"},
+		{"1 GB", "MiB", 2, "953.67 MiB"},
+		{"2500 MHz", "GHz", 1, "2.5 GHz"},
+		{"90 s", "m", 3, "1.500 m"},
+	}
+	for _, test := range tests {
+		out, err := test.in.Format(test.unit, test.prec)
+		if err != nil {
+			t.Errorf("%v: %v", test.in, err)
+			continue
+		}
+		if out != test.out {
+			t.Errorf("%v in %v: expected %q, got %q", test.in, test.unit, test.out, out)
+		}
+	}
+}
+
+func TestScalarFormatCrossDimension(t *testing.T) {
+	if _, err := Scalar("1 GiB").Format("GHz", 1); err == nil {
+		t.Error("formatting a size in GHz should fail")
+	}
+	if _, err := Scalar("10 s").Format("MB", 1); err == nil {
+		t.Error("formatting a duration in MB should fail")
+	}
+}
+
+func TestMinMaxScalar(t *testing.T) {
+	sizes := []Scalar{"2 GB", "1 GiB", "1500 MB"}
+	min, err := MinScalar(sizes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min != "1 GiB" {
+		t.Errorf("expected 1 GiB, got %v", min)
+	}
+	max, err := MaxScalar(sizes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max != "2 GB" {
+		t.Errorf("expected 2 GB, got %v", max)
+	}
+	if _, err := MinScalar(); err == nil {
+		t.Error("MinScalar without argument should fail")
+	}
+}
+
+func TestMinMaxScalarMixed(t *testing.T) {
+	if _, err := MinScalar("1 GiB", "1 GHz"); err == nil {
+		t.Error("comparing a size with a frequency should fail")
+	}
+	if _, err := MaxScalar("10 s", "1 GiB"); err == nil {
+		t.Error("comparing a duration with a size should fail")
+	}
+}
+
+const normalizeTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    memory:
+      type: scalar-unit.size
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            mem_size: 4 GB
+    storage:
+      type: tosca.nodes.BlockStorage
+      properties:
+        size: 1 KiB
+    volume:
+      type: tosca.nodes.BlockStorage
+      properties:
+        size: { get_input: memory }
+`
+
+func TestNormalizeScalars(t *testing.T) {
+	s := parseString(t, normalizeTemplate)
+	err := s.NormalizeScalars()
+	if err == nil || !strings.Contains(err.Error(), "volume.size") {
+		t.Fatalf("the unresolved volume.size should be reported, got %v", err)
+	}
+	size := s.TopologyTemplate.NodeTemplates["storage"].Properties["size"]["value"][0]
+	if size != "1024 B" {
+		t.Fatalf("expected 1024 B, got %v", size)
+	}
+	if _, ok := s.TopologyTemplate.NodeTemplates["volume"].Properties["size"]["get_input"]; !ok {
+		t.Fatal("the function of volume.size should be left untouched")
+	}
+}
+
+func TestParseScalars(t *testing.T) {
+	scalars, errs := ParseScalars([]string{"1 GiB", "2 parsecs", "512 MB", "42"})
+	if len(scalars) != 4 || len(errs) != 4 {
+		t.Fatalf("the results should be parallel to the values, got %v and %v", scalars, errs)
+	}
+	for i, valid := range []bool{true, false, true, false} {
+		if valid && (errs[i] != nil || scalars[i] == "") {
+			t.Errorf("value %v should be valid, got %q and %v", i, scalars[i], errs[i])
+		}
+		if !valid && (errs[i] == nil || scalars[i] != "") {
+			t.Errorf("value %v should be invalid, got %q and %v", i, scalars[i], errs[i])
+		}
+	}
+	if !strings.Contains(errs[1].Error(), "parsecs") {
+		t.Errorf("the error should name the value, got %v", errs[1])
+	}
+	if _, errs := ParseScalars([]string{"1 GiB", "2 GHz"}); errs != nil {
+		t.Errorf("valid values should not return errors, got %v", errs)
+	}
+}
+
+func TestSortScalars(t *testing.T) {
+	scalars := []Scalar{"2 GB", "1 GiB", "1000 MB", "512 MiB", "1 GB"}
+	if err := SortScalars(scalars); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Scalar{"512 MiB", "1000 MB", "1 GB", "1 GiB", "2 GB"}
+	if !reflect.DeepEqual(scalars, expected) {
+		t.Fatalf("expected %v, got %v", expected, scalars)
+	}
+}
+
+func TestSortScalarsMixed(t *testing.T) {
+	scalars := []Scalar{"2 GB", "1 GHz", "1 GiB"}
+	if err := SortScalars(scalars); err == nil {
+		t.Fatal("sorting a size and a frequency should fail")
+	}
+	if !reflect.DeepEqual(scalars, []Scalar{"2 GB", "1 GHz", "1 GiB"}) {
+		t.Fatalf("the slice should be left untouched, got %v", scalars)
+	}
+}
+
+func TestScalarCanonicalKey(t *testing.T) {
+	key := func(s Scalar) string {
+		k, err := s.CanonicalKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	if key("1 KiB") != key("1024 B") {
+		t.Errorf("1 KiB and 1024 B should have the same key, got %v and %v", key("1 KiB"), key("1024 B"))
+	}
+	if key("1 KiB") == key("2 KiB") {
+		t.Error("1 KiB and 2 KiB should have different keys")
+	}
+	if key("1 s") != "1000000000 ns" || key("2 GHz") != "2000000000 Hz" {
+		t.Errorf("bad canonical keys %v and %v", key("1 s"), key("2 GHz"))
+	}
+	if _, err := Scalar("1 parsec").CanonicalKey(); err == nil {
+		t.Error("an invalid scalar should not have a canonical key")
+	}
+}
+
+func TestScalarArithmetic(t *testing.T) {
+	sum, err := Scalar("1 GiB").Add("512 MiB")
+	if err != nil || sum != "1610612736 B" {
+		t.Errorf("1 GiB + 512 MiB: expected 1610612736 B, got %v (%v)", sum, err)
+	}
+	diff, err := Scalar("2 GiB").Sub("512 MiB")
+	if err != nil || diff != "1610612736 B" {
+		t.Errorf("2 GiB - 512 MiB: expected 1610612736 B, got %v (%v)", diff, err)
+	}
+	if _, err := Scalar("512 MiB").Sub("2 GiB"); err == nil {
+		t.Error("a negative difference should fail")
+	}
+	if _, err := Scalar("2 GiB").Sub("1 GHz"); err == nil {
+		t.Error("subtracting a frequency from a size should fail")
+	}
+	prod, err := Scalar("1 GiB").Mul(3)
+	if err != nil || prod != "3221225472 B" {
+		t.Errorf("1 GiB * 3: expected 3221225472 B, got %v (%v)", prod, err)
+	}
+	if zero, err := Scalar("1 GiB").Mul(0); err != nil || zero != "0 B" {
+		t.Errorf("1 GiB * 0: expected 0 B, got %v (%v)", zero, err)
+	}
+	if _, err := Scalar("1 GiB").Mul(-1); err == nil {
+		t.Error("a negative factor should fail")
+	}
+	if _, err := Scalar("1 TiB").Mul(1e10); err == nil {
+		t.Error("an overflow should fail")
+	}
+}
+
+func TestScalarDivideBy(t *testing.T) {
+	ratio, err := Scalar("512 MiB").DivideBy("1 GiB")
+	if err != nil || ratio != 0.5 {
+		t.Errorf("512 MiB / 1 GiB: expected 0.5, got %v (%v)", ratio, err)
+	}
+	if _, err := Scalar("1 GiB").DivideBy("1 GHz"); err == nil {
+		t.Error("dividing a size by a frequency should fail")
+	}
+	if _, err := Scalar("1 GiB").DivideBy("0 MB"); err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Errorf("dividing by zero should fail, got %v", err)
+	}
+}
+
+func TestDecodeScalarFields(t *testing.T) {
+	type limits struct {
+		Timeout interface{} `yaml:"timeout" tosca:"scalar"`
+	}
+	var config struct {
+		Name   string      `yaml:"name"`
+		Memory interface{} `yaml:"memory" tosca:"scalar"`
+		Limits limits      `yaml:"limits"`
+	}
+	doc := "name: cache\nmemory: 512 MiB\nlimits:\n  timeout: 30 s\n"
+	if err := yaml.Unmarshal([]byte(doc), &config); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeScalarFields(&config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Memory != Size(536870912) {
+		t.Errorf("memory: expected Size(536870912), got %v (%T)", config.Memory, config.Memory)
+	}
+	if config.Limits.Timeout != 30*time.Second {
+		t.Errorf("timeout: expected 30s, got %v (%T)", config.Limits.Timeout, config.Limits.Timeout)
+	}
+	if config.Name != "cache" {
+		t.Errorf("the untagged field name should be left as is, got %q", config.Name)
+	}
+	if err := DecodeScalarFields(&config); err != nil {
+		t.Errorf("decoding twice should be a no-op, got %v", err)
+	}
+	config.Memory = "512 parsecs"
+	if err := DecodeScalarFields(&config); err == nil || !strings.Contains(err.Error(), "Field Memory") {
+		t.Errorf("expected an error on the field Memory, got %v", err)
+	}
+	var bad struct {
+		Memory string `tosca:"scalar"`
+	}
+	if err := DecodeScalarFields(&bad); err == nil || !strings.Contains(err.Error(), "Field Memory: a scalar field must be of type interface{}") {
+		t.Errorf("a scalar field of type string should be rejected, got %v", err)
+	}
+	if err := DecodeScalarFields(config); err == nil {
+		t.Error("a struct that is not a pointer should be rejected")
+	}
+}
+
+func TestDecodeScalarFieldsTyped(t *testing.T) {
+	var config struct {
+		MemoryText  string        `yaml:"memory"`
+		Memory      Size          `yaml:"-" tosca:"scalar,from=MemoryText"`
+		TimeoutText string        `yaml:"timeout"`
+		Timeout     time.Duration `yaml:"-" tosca:"scalar,from=TimeoutText"`
+	}
+	if err := yaml.Unmarshal([]byte("memory: 512 MiB\ntimeout: 30 s\n"), &config); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeScalarFields(&config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Memory != 536870912 || config.Timeout != 30*time.Second {
+		t.Fatalf("expected 536870912 B and 30s, got %v and %v", config.Memory, config.Timeout)
+	}
+	config.MemoryText = "30 s"
+	if err := DecodeScalarFields(&config); err == nil || !strings.Contains(err.Error(), "Field Memory: 30 s is a time.Duration, not a toscalib.Size") {
+		t.Errorf("a duration should not decode into a size, got %v", err)
+	}
+	for _, bad := range []interface{}{
+		&struct {
+			Memory Size `tosca:"scalar"`
+		}{},
+		&struct {
+			Memory Frequency `tosca:"scalar,from=Missing"`
+		}{},
+		&struct {
+			Count  int
+			Memory time.Duration `tosca:"scalar,from=Count"`
+		}{},
+	} {
+		if err := DecodeScalarFields(bad); err == nil || !strings.HasPrefix(err.Error(), "Field Memory: ") {
+			t.Errorf("%T: expected the field to be rejected, got %v", bad, err)
+		}
+	}
+}
+
+func TestScalarParsed(t *testing.T) {
+	tests := []struct {
+		scalar Scalar
+		value  float64
+		unit   string
+		kind   ScalarKind
+	}{
+		{"1.5 GiB", 1.5, "GiB", KindSize},
+		{"2kHz", 2, "kHz", KindFrequency},
+		{" 10 ms ", 10, "ms", KindTime},
+	}
+	for _, test := range tests {
+		value, unit, kind, err := test.scalar.Parsed()
+		if err != nil || value != test.value || unit != test.unit || kind != test.kind {
+			t.Errorf("%q: expected (%v, %v, %v), got (%v, %v, %v, %v)", test.scalar, test.value, test.unit, test.kind, value, unit, kind, err)
+		}
+	}
+	if _, _, _, err := Scalar("42").Parsed(); err != ErrMissingUnit {
+		t.Errorf("expected ErrMissingUnit, got %v", err)
+	}
+	for _, s := range []Scalar{"1 XB", "1.2.3 GB", "GB"} {
+		if _, _, _, err := s.Parsed(); err == nil {
+			t.Errorf("%q should not be parsed", s)
+		}
+	}
+}
+
+func TestScalarValue(t *testing.T) {
+	v, err := Scalar("1.5 GB").Value()
+	if err != nil || v != 1.5 {
+		t.Errorf("1.5 GB: expected 1.5, got %v (%v)", v, err)
+	}
+	for _, s := range []Scalar{"1.5 parsec", "1.5", "GB"} {
+		if _, err := s.Value(); err == nil {
+			t.Errorf("%q should not have a value", s)
+		}
+	}
+}
+
+func TestScalarKind(t *testing.T) {
+	for scalar, expected := range map[Scalar]ScalarKind{"1.5 GiB": KindSize, "2 kHz": KindFrequency, "10 ms": KindTime} {
+		if kind, err := scalar.Kind(); err != nil || kind != expected {
+			t.Errorf("%q: expected %v, got %v (%v)", scalar, expected, kind, err)
+		}
+	}
+	if _, err := Scalar("10 parsec").Kind(); err == nil {
+		t.Error("a scalar with an unknown unit should not have a kind")
+	}
+}
+
+func TestSplitScalar(t *testing.T) {
+	for in, expected := range map[string][2]string{
+		"  1.5   GiB ": {"1.5", "GiB"},
+		"1.5\tGiB":     {"1.5", "GiB"},
+		"1.5GiB":       {"1.5", "GiB"},
+		"10 parsec":    {"10", "parsec"},
+	} {
+		value, unit, err := SplitScalar(in)
+		if err != nil || value != expected[0] || unit != expected[1] {
+			t.Errorf("%q: expected %v, got (%v, %v, %v)", in, expected, value, unit, err)
+		}
+	}
+	if _, _, err := SplitScalar("42"); err != ErrMissingUnit {
+		t.Errorf("expected ErrMissingUnit, got %v", err)
+	}
+	for _, in := range []string{"", "   ", "GiB", "1.5 Gi B", "-1 GB"} {
+		if _, _, err := SplitScalar(in); err == nil {
+			t.Errorf("%q should not be split", in)
+		}
+	}
+}
+
+func TestScalarEqualNative(t *testing.T) {
+	if ok, err := Scalar("5 s").EqualDuration(5 * time.Second); !ok || err != nil {
+		t.Errorf("5 s should equal 5s, got %v (%v)", ok, err)
+	}
+	if ok, err := Scalar("5000 ms").EqualDuration(4 * time.Second); ok || err != nil {
+		t.Errorf("5000 ms should not equal 4s, got %v (%v)", ok, err)
+	}
+	if ok, err := Scalar("1 KiB").EqualBytes(1024); !ok || err != nil {
+		t.Errorf("1 KiB should equal 1024 bytes, got %v (%v)", ok, err)
+	}
+	if ok, err := Scalar("1 kB").EqualBytes(1024); ok || err != nil {
+		t.Errorf("1 kB should not equal 1024 bytes, got %v (%v)", ok, err)
+	}
+	if _, err := Scalar("1 KiB").EqualDuration(time.Second); err == nil {
+		t.Error("a size should not be compared with a duration")
+	}
+	if _, err := Scalar("5 s").EqualBytes(5); err == nil {
+		t.Error("a duration should not be compared with a size")
+	}
+}
+
+func TestScalarDecimalComma(t *testing.T) {
+	if _, err := Scalar("1,5 GB").Evaluate(); err == nil {
+		t.Error("a decimal comma should be rejected by default")
+	}
+	if _, err := Scalar("1,5 GB").EvaluateWithOptions(ScalarOptions{}); err == nil {
+		t.Error("a decimal comma should be rejected without the DecimalComma option")
+	}
+	v, err := Scalar(" 1,5 GB").EvaluateWithOptions(ScalarOptions{DecimalComma: true})
+	if err != nil || v != Size(1500000000) {
+		t.Errorf("1,5 GB: expected 1.5 GB, got %v (%v)", v, err)
+	}
+	v, err = Scalar("2.5 GB").EvaluateWithOptions(ScalarOptions{DecimalComma: true})
+	if err != nil || v != Size(2500000000) {
+		t.Errorf("2.5 GB: a decimal point should still be accepted, got %v (%v)", v, err)
+	}
+	if _, err := Scalar("1,500,000 B").EvaluateWithOptions(ScalarOptions{DecimalComma: true}); err == nil {
+		t.Error("several commas should be rejected")
+	}
+}
+
+func TestScalarUppercaseKilo(t *testing.T) {
+	if _, err := Scalar("4 KB").Evaluate(); err == nil {
+		t.Error("KB should be rejected by default")
+	}
+	v, err := Scalar("4 KB").EvaluateWithOptions(ScalarOptions{UppercaseKilo: true})
+	if err != nil || v != Size(4000) {
+		t.Errorf("4 KB: expected 4000 bytes, got %v (%v)", v, err)
+	}
+	v, err = Scalar("1,5 KB").EvaluateWithOptions(ScalarOptions{DecimalComma: true, UppercaseKilo: true})
+	if err != nil || v != Size(1500) {
+		t.Errorf("1,5 KB: expected 1500 bytes, got %v (%v)", v, err)
+	}
+	if _, err := Scalar("4 KiB").EvaluateWithOptions(ScalarOptions{UppercaseKilo: true}); err != nil {
+		t.Errorf("KiB should still be accepted, got %v", err)
+	}
+}
+
+func TestScalarText(t *testing.T) {
+	type volume struct {
+		Size   Scalar            `json:"size"`
+		Quotas map[Scalar]string `json:"quotas"`
+	}
+	in := volume{Size: "1.5 GiB", Quotas: map[Scalar]string{"10 GB": "small"}}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"size":"1.5 GiB","quotas":{"10 GB":"small"}}` {
+		t.Fatalf("bad JSON %s", data)
+	}
+	var out volume
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("expected %v after the round trip, got %v", in, out)
+	}
+	if err := json.Unmarshal([]byte(`{"size":"1.5 parsecs"}`), &out); err == nil {
+		t.Fatal("an invalid scalar should not unmarshal")
+	}
+	if _, err := json.Marshal(volume{Size: "42"}); err == nil {
+		t.Fatal("an invalid scalar should not marshal")
+	}
+	data, err = json.Marshal(volume{})
+	if err != nil || json.Unmarshal(data, &out) != nil || out.Size != "" {
+		t.Fatalf("the empty scalar should round trip, got %s (%v)", data, err)
+	}
+}
+
+func TestNormalizeSizes(t *testing.T) {
+	values, err := NormalizeSizes([]Scalar{"1 KiB", "2 kB", "1.5 MB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []float64{1024, 2000, 1500000}) {
+		t.Fatalf("expected [1024 2000 1500000], got %v", values)
+	}
+	_, err = NormalizeSizes([]Scalar{"1 KiB", "2 GHz"})
+	if err == nil || !strings.Contains(err.Error(), "2 GHz at index 1 is a scalar-unit.frequency") {
+		t.Fatalf("expected a heterogeneous slice error, got %v", err)
+	}
+	values, err = NormalizeKind(KindTime, []Scalar{"1 ms", "2 s"})
+	if err != nil || !reflect.DeepEqual(values, []float64{1e6, 2e9}) {
+		t.Fatalf("expected [1e6 2e9], got %v (%v)", values, err)
+	}
+}
+
+func TestScalarRoundTo(t *testing.T) {
+	tests := map[Scalar]Scalar{
+		"1600 MiB": "2 GiB",
+		"1500 MiB": "1 GiB",
+		"1536 MiB": "2 GiB",
+		"90 s":     "2 m",
+		"1400 MHz": "1 GHz",
+		"10 MB":    "10 MB",
+	}
+	for s, expected := range tests {
+		unit := strings.Fields(string(expected))[1]
+		r, err := s.RoundTo(unit)
+		if err != nil || r != expected {
+			t.Errorf("%q rounded to %v: expected %q, got %q (%v)", s, unit, expected, r, err)
+		}
+	}
+	if _, err := Scalar("1 GiB").RoundTo("GHz"); err == nil {
+		t.Error("rounding a size to a frequency unit should fail")
+	}
+}
+
+func TestScalarClamp(t *testing.T) {
+	tests := map[Scalar]Scalar{
+		"512 MiB":  "1 GiB",
+		"2 GB":     "2 GB",
+		"1 GiB":    "1 GiB",
+		"16384 MB": "8 GiB",
+	}
+	for s, expected := range tests {
+		r, err := s.Clamp("1 GiB", "8 GiB")
+		if err != nil || r != expected {
+			t.Errorf("%q clamped to [1 GiB, 8 GiB]: expected %q, got %q (%v)", s, expected, r, err)
+		}
+	}
+	if _, err := Scalar("2 GB").Clamp("1 GHz", "8 GiB"); err == nil {
+		t.Error("clamping to a frequency should fail")
+	}
+	if _, err := Scalar("2 GB").Clamp("1 GiB", "8 s"); err == nil {
+		t.Error("clamping to a duration should fail")
+	}
+	if _, err := Scalar("2 GB").Clamp("8 GiB", "1 GiB"); err == nil {
+		t.Error("an empty range should fail")
+	}
+}
+
+func TestScalarSizeRange(t *testing.T) {
+	sizes, err := ScalarSizeRange("1 GiB", "4 GiB", "1 GiB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Scalar{"1 GiB", "2 GiB", "3 GiB", "4 GiB"}; !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("expected %v, got %v", expected, sizes)
+	}
+	sizes, err = ScalarSizeRange("1 GiB", "2 GiB", "384 MiB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Scalar{"1 GiB", "1.375 GiB", "1.75 GiB"}; !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("expected %v, got %v", expected, sizes)
+	}
+	for _, tc := range []struct {
+		start, end, step Scalar
+		err              string
+	}{
+		{"1 GiB", "4 GiB", "0 B", "the step must be positive"},
+		{"1 GiB", "4 GiB", "1 s", "not a scalar-unit.size"},
+		{"1 B", "1 TiB", "1 B", "more than 10000 sizes"},
+	} {
+		_, err := ScalarSizeRange(tc.start, tc.end, tc.step)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v to %v by %v: expected an error containing %q, got %v", tc.start, tc.end, tc.step, tc.err, err)
+		}
+	}
+}
+
+func TestSumSizes(t *testing.T) {
+	total, err := SumSizes([]Scalar{"1 GiB", "512 MiB", "1 kB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1610613736 {
+		t.Errorf("expected 1610613736 bytes, got %v", total)
+	}
+	if total, err := SumSizes(nil); err != nil || total != 0 {
+		t.Errorf("the sum of no size should be 0, got %v, %v", total, err)
+	}
+	_, err = SumSizes([]Scalar{"4000 PiB", "4000 PiB", "4000 PiB"})
+	if err == nil || !strings.Contains(err.Error(), "Scalar 4000 PiB at index 2: the sum of the sizes overflows") {
+		t.Errorf("expected an overflow error, got %v", err)
+	}
+	_, err = SumSizes([]Scalar{"1 GiB", "2 GHz"})
+	if err == nil || !strings.Contains(err.Error(), "is a scalar-unit.frequency, not a scalar-unit.size") {
+		t.Errorf("expected a kind error, got %v", err)
+	}
+}
+
+func TestScalarNormalizeInt(t *testing.T) {
+	for _, tc := range []struct {
+		scalar Scalar
+		value  uint64
+		unit   string
+	}{
+		{"8 PiB", 9007199254740992, "B"},
+		{"8.5 PiB", 9570149208162304, "B"},
+		{"9007199254740993 B", 9007199254740993, "B"},
+		{"16000 PiB", 18014398509481984000, "B"},
+		{"1.5 B", 1, "B"},
+		{"2.5 kHz", 2500, "Hz"},
+		{"1.5 s", 1500000000, "ns"},
+	} {
+		value, unit, err := tc.scalar.NormalizeInt()
+		if err != nil {
+			t.Fatalf("%v: %v", tc.scalar, err)
+		}
+		if value != tc.value || unit != tc.unit {
+			t.Errorf("%v: expected %v %v, got %v %v", tc.scalar, tc.value, tc.unit, value, unit)
+		}
+	}
+	// The float path agrees while the values are exactly representable
+	v, _ := Scalar("8 PiB").Evaluate()
+	if uint64(v.(Size)) != 9007199254740992 {
+		t.Errorf("8 PiB evaluated to %v", v)
+	}
+	// and loses the last byte beyond 2^53
+	v, _ = Scalar("9007199254740993 B").Evaluate()
+	if uint64(v.(Size)) == 9007199254740993 {
+		t.Errorf("the float path was expected to round 9007199254740993 B, got %v", v)
+	}
+	for _, s := range []Scalar{"16384 PiB", "1 XB", "42", "1.2.3 GB"} {
+		if _, _, err := s.NormalizeInt(); err == nil {
+			t.Errorf("%v should not be normalized", s)
+		}
+	}
+}
+
+func TestUnitKind(t *testing.T) {
+	for unit, expected := range map[string]ScalarKind{
+		"GiB": KindSize,
+		"kB":  KindSize,
+		"MHz": KindFrequency,
+		"ms":  KindDuration,
+		"d":   KindDuration,
+		"foo": KindUnknown,
+		"gib": KindUnknown,
+		"":    KindUnknown,
+	} {
+		if kind := UnitKind(unit); kind != expected {
+			t.Errorf("%q: expected the kind %q, got %q", unit, expected, kind)
+		}
+	}
+}
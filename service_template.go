@@ -16,7 +16,9 @@ limitations under the License.
 package toscalib
 
 import (
+	"errors"
 	"fmt"
+	yaml3 "gopkg.in/yaml.v3"
 	"reflect"
 )
 
@@ -24,8 +26,9 @@ import (
 //http://docs.oasis-open.org/tosca/TOSCA-Simple-Profile-YAML/v1.0/csd03/TOSCA-Simple-Profile-YAML-v1.0-csd03.html
 type ServiceTemplateDefinition struct {
 	DefinitionsVersion Version                         `yaml:"tosca_definitions_version" json:"tosca_definitions_version"` // A.9.3.1 tosca_definitions_version
+	Metadata           map[string]string               `yaml:"metadata,omitempty" json:"metadata,omitempty"`               // Defines a section used to declare additional metadata information such as template_name, template_author and template_version.
 	Description        string                          `yaml:"description,omitempty" json:"description,omitempty"`
-	Imports            []string                        `yaml:"imports,omitempty" json:"imports,omitempty"`                       // Declares import statements external TOSCA Definitions documents. For example, these may be file location or URIs relative to the service template file within the same TOSCA CSAR file.
+	Imports            []Import                        `yaml:"imports,omitempty" json:"imports,omitempty"`                       // Declares import statements external TOSCA Definitions documents. For example, these may be file location or URIs relative to the service template file within the same TOSCA CSAR file.
 	Repositories       map[string]RepositoryDefinition `yaml:"repositories,omitempty" json:"repositories,omitempty"`             // Declares the list of external repositories which contain artifacts that are referenced in the service template along with their addresses and necessary credential information used to connect to them in order to retrieve the artifacts.
 	DataTypes          map[string]DataType             `yaml:"data_types,omitempty" json:"data_types,omitempty"`                 // Declares a list of optional TOSCA Data Type definitions.
 	NodeTypes          map[string]NodeType             `yaml:"node_types,omitempty" json:"node_types,omitempty"`                 // This section contains a set of node type definitions for use in service templates.
@@ -35,6 +38,25 @@ type ServiceTemplateDefinition struct {
 	DlsDefinitions     interface{}                     `yaml:"dsl_definitions,omitempty" json:"dsl_definitions,omitempty"`       // Declares optional DSL-specific definitions and conventions.  For example, in YAML, this allows defining reusable YAML macros (i.e., YAML alias anchors) for use throughout the TOSCA Service Template.
 	InterfaceTypes     map[string]InterfaceType        `yaml:"interface_types,omitempty" json:"interface_types,omitempty"`       // This section contains an optional list of interface type definitions for use in service templates.
 	TopologyTemplate   TopologyTemplateType            `yaml:"topology_template" json:"topology_template"`                       // Defines the topology template of an application or service, consisting of node templates that represent the application’s or service’s components, as well as relationship templates representing relations between the components.
+	flatNodeTypes      map[string]NodeType             // Cache of the flattened node types
+	source             *yaml3.Node                     // The parsed YAML document, used to locate the definitions in the source
+}
+
+// ErrNotAVersion is returned when the template_version metadata does not follow the TOSCA version grammar
+var ErrNotAVersion = errors.New("The template_version metadata is not a TOSCA version")
+
+// TemplateVersion returns the template_version metadata of the service template, or an empty version if it is not set.
+// It returns ErrNotAVersion if the value is set but is not a TOSCA version, such as a date;
+// callers may then use the raw Metadata["template_version"] string instead.
+func (s *ServiceTemplateDefinition) TemplateVersion() (Version, error) {
+	v, ok := s.Metadata["template_version"]
+	if !ok {
+		return "", nil
+	}
+	if !Version(v).IsValid() {
+		return "", ErrNotAVersion
+	}
+	return Version(v), nil
 }
 
 type PA struct {
@@ -56,6 +78,39 @@ func (s *ServiceTemplateDefinition) GetProperty(node, prop string) PA {
 }
 
 func (s *ServiceTemplateDefinition) EvaluateStatement(i interface{}) (interface{}, error) {
+	return s.evaluate(i, nil)
+}
+
+// EvaluateStatementWithOptions evaluates the statement i according to opts.
+// The inputs without value evaluate to their default value.
+func (s *ServiceTemplateDefinition) EvaluateStatementWithOptions(i interface{}, opts EvaluateOptions) (interface{}, error) {
+	return s.evaluate(i, &Context{Options: opts})
+}
+
+// intrinsicFunctions are the names of the functions a value may call:
+// a map value with one of them as its single key is a function call
+var intrinsicFunctions = map[string]bool{
+	"concat":               true,
+	"token":                true,
+	"get_input":            true,
+	"get_property":         true,
+	"get_attribute":        true,
+	"get_operation_output": true,
+	"get_nodes_of_type":    true,
+	"get_artifact":         true,
+	"add":                  true,
+	"subtract":             true,
+	"multiply":             true,
+	"divide":               true,
+	"$add":                 true,
+	"$subtract":            true,
+	"$multiply":            true,
+	"$divide":              true,
+}
+
+// evaluate evaluates the statement i, the inputs of ctx override the inputs of the template.
+// ctx may be nil.
+func (s *ServiceTemplateDefinition) evaluate(i interface{}, ctx *Context) (interface{}, error) {
 	if ww, ok := i.(PA); ok {
 		w := ww.PA
 		for k, v := range w {
@@ -91,26 +146,67 @@ func (s *ServiceTemplateDefinition) EvaluateStatement(i interface{}) (interface{
 							}
 
 						}
-						o, _ := s.EvaluateStatement(PA{PA: paa, Origin: ww.Origin})
+						o, _ := s.evaluate(PA{PA: paa, Origin: ww.Origin}, ctx)
 						output = fmt.Sprintf("%s%s", output, o)
 					}
 				}
 				return output, nil
+			case "add", "subtract", "multiply", "divide", "$add", "$subtract", "$multiply", "$divide":
+				return s.arithmetic(k, v, ww.Origin, ctx)
 			case "get_input":
+				if ctx != nil {
+					return ctx.input(s, v[0].(string)), nil
+				}
 				return s.TopologyTemplate.Inputs[v[0].(string)].Value, nil
 				// Find the inputs and returns it
 			case "get_property":
 				node := v[0].(string)
+				if len(v) == 3 {
+					// get_property: [ node, capability, property ]
+					val, err := s.GetCapabilityProperty(node, fmt.Sprint(v[1]), fmt.Sprint(v[2]))
+					if err != nil {
+						return nil, err
+					}
+					if pa, ok := val.(PropertyAssignment); ok {
+						return s.evaluate(PA{PA: pa, Origin: node}, ctx)
+					}
+					return val, nil
+				}
 				pa := s.GetProperty(node, v[1].(string))
-				st, _ := s.EvaluateStatement(pa)
+				st, _ := s.evaluate(pa, ctx)
 				return st, nil
+			case "get_artifact":
+				return s.getArtifact(v, ww.Origin)
 				/*
 					case "get_attribute":
 						ret := append([]string{"get_attribute"}, v...)
 						return ret, nil
 				*/
+			default:
+				if ctx != nil && ctx.Options.UnknownFunctions == StrictFunctions {
+					return nil, fmt.Errorf("Unknown function %v", k)
+				}
+				return w, nil
 			}
 		}
 	}
 	return []string{}, nil
 }
+
+// derivesFrom walks the derivation chain of the type name using parent and
+// returns true if ancestor is found
+func derivesFrom(name, ancestor string, parent func(string) (string, bool)) bool {
+	visited := make(map[string]bool)
+	for n := name; n != "" && !visited[n]; {
+		if n == ancestor {
+			return true
+		}
+		visited[n] = true
+		p, ok := parent(n)
+		if !ok {
+			return false
+		}
+		n = p
+	}
+	return false
+}
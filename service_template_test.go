@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -75,3 +76,27 @@ func TestParseCsar(t *testing.T) {
 }
 
 func TestEvaluate(t *testing.T) {}
+
+func TestTemplateVersion(t *testing.T) {
+	doc := `tosca_definitions_version: tosca_simple_yaml_1_0
+metadata:
+  template_name: app
+  template_version: 1.2.0.beta-3
+`
+	s := parseString(t, doc)
+	v, err := s.TemplateVersion()
+	if err != nil || v != "1.2.0.beta-3" {
+		t.Fatalf("expected version 1.2.0.beta-3, got %q (%v)", v, err)
+	}
+	s = parseString(t, strings.Replace(doc, "1.2.0.beta-3", "2016-03-14", 1))
+	if _, err := s.TemplateVersion(); err != ErrNotAVersion {
+		t.Fatalf("a date should not be a version, got %v", err)
+	}
+	if s.Metadata["template_version"] != "2016-03-14" {
+		t.Fatalf("the raw template_version should be kept, got %q", s.Metadata["template_version"])
+	}
+	s = parseString(t, "tosca_definitions_version: tosca_simple_yaml_1_0\n")
+	if v, err := s.TemplateVersion(); v != "" || err != nil {
+		t.Fatalf("a missing template_version should be empty, got %q (%v)", v, err)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	yaml3 "gopkg.in/yaml.v3"
+	"strconv"
+)
+
+// line returns the line of the YAML source where the mapping key or the sequence element at the end of path is defined.
+// If the path is only partly found, the line of its deepest known element is returned.
+// It returns 0 if the source is unknown.
+func (s *ServiceTemplateDefinition) line(path ...string) int {
+	line, _ := s.locate(path...)
+	return line
+}
+
+// exactLine returns the line of the YAML source where the element at the end of path is defined,
+// or 0 if the source is unknown or the path is not entirely found.
+func (s *ServiceTemplateDefinition) exactLine(path ...string) int {
+	if line, found := s.locate(path...); found {
+		return line
+	}
+	return 0
+}
+
+// locate returns the line of the deepest element of path found in the YAML source
+// and whether the whole path was found.
+func (s *ServiceTemplateDefinition) locate(path ...string) (int, bool) {
+	if s.source == nil {
+		return 0, false
+	}
+	node := s.source
+	if node.Kind == yaml3.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := node.Line
+	for _, key := range path {
+		var next *yaml3.Node
+		switch node.Kind {
+		case yaml3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml3.SequenceNode:
+			// The elements of a sequence are given by their index
+			if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(node.Content) {
+				next = node.Content[i]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			return line, false
+		}
+		node = next
+	}
+	return line, true
+}
@@ -18,7 +18,24 @@ package toscalib
 // TopologyTemplateType as described in appendix A 8
 // This section defines the topology template of a cloud application. The main ingredients of the topology template are node templates representing components of the application and relationship templates representing links between the components. These elements are defined in the nested node_templates section and the nested relationship_templates sections, respectively.  Furthermore, a topology template allows for defining input parameters, output parameters as well as grouping of node templates.
 type TopologyTemplateType struct {
-	Inputs        map[string]PropertyDefinition `yaml:"inputs,omitempty" json:"inputs,omitempty"`
-	NodeTemplates map[string]NodeTemplate       `yaml:"node_templates" json:"node_templates"`
-	Outputs       map[string]Output             `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+	Inputs                map[string]PropertyDefinition   `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	NodeTemplates         map[string]NodeTemplate         `yaml:"node_templates" json:"node_templates"`
+	RelationshipTemplates map[string]RelationshipTemplate `yaml:"relationship_templates,omitempty" json:"relationship_templates,omitempty"`
+	Outputs               map[string]Output               `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+	SubstitutionMappings  SubstitutionMapping             `yaml:"substitution_mappings,omitempty" json:"substitution_mappings,omitempty"`
+	Policies              []map[string]PolicyDefinition   `yaml:"policies,omitempty" json:"policies,omitempty"`
+	Workflows             map[string]Workflow             `yaml:"workflows,omitempty" json:"workflows,omitempty"`
+}
+
+// SubstitutionMapping as described in Appendix 9.2
+// A substitution mapping exposes the topology template as an implementation of a node type.
+// Each mapping is a list whose first element is the name of a node template of the topology
+// followed by the name of its capability, requirement, property or attribute.
+// A property mapping may also be a list holding the name of an input of the topology.
+type SubstitutionMapping struct {
+	NodeType     string              `yaml:"node_type,omitempty" json:"node_type,omitempty"`       // The required name of the Node Type the Topology Template is providing an implementation for.
+	Capabilities map[string][]string `yaml:"capabilities,omitempty" json:"capabilities,omitempty"` // The optional map of capabilities of the Node Type to capabilities of the node templates.
+	Requirements map[string][]string `yaml:"requirements,omitempty" json:"requirements,omitempty"` // The optional map of requirements of the Node Type to requirements of the node templates.
+	Properties   map[string][]string `yaml:"properties,omitempty" json:"properties,omitempty"`     // The optional map of properties of the Node Type to properties of the node templates or inputs.
+	Attributes   map[string][]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`     // The optional map of attributes of the Node Type to attributes of the node templates.
 }
@@ -18,3 +18,21 @@ package toscalib
 // CredentialDefinition as described in appendix C 2.1
 // The Credential type is a complex TOSCA data Type used when describing authorization credentials used to access network accessible resources.
 type CredentialDefinition interface{}
+
+// primitiveTypes are the TOSCA primitive and special types of Appendix 2.
+// They are not defined by a data type definition.
+var primitiveTypes = map[string]bool{
+	"string":                true,
+	"integer":               true,
+	"float":                 true,
+	"boolean":               true,
+	"timestamp":             true,
+	"null":                  true,
+	"version":               true,
+	"range":                 true,
+	"list":                  true,
+	"map":                   true,
+	"scalar-unit.size":      true,
+	"scalar-unit.frequency": true,
+	"scalar-unit.time":      true,
+}
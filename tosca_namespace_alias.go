@@ -21,7 +21,6 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
-	"strings"
 )
 
 // This implements the type defined in Appendix A 2 of the definition file
@@ -35,6 +34,13 @@ import (
 //BuildVersion is an optional integer value greater than or equal to 0 (zero) that can be used to further qualify different build versions of the code that has the same qualifer_string
 type Version string
 
+var isVersion = regexp.MustCompile(`^\d+\.\d+(\.\d+(\.[[:alnum:]_]+(-\d+)?)?)?$`)
+
+// IsValid returns true if the version follows the TOSCA version grammar
+func (v Version) IsValid() bool {
+	return isVersion.MatchString(string(v))
+}
+
 /*TODO
 // GetMajor returns the major_version number
 func (toscaVersion *Version) GetMajor() int {
@@ -71,11 +77,180 @@ func (toscaVersion *Version) GetBuildVersion() int {
 */
 
 // UNBOUNDED: A.2.3 TOCSA range type
+// UNBOUNDED is the sentinel used as the upper bound of an open range.
+// Its value is the greatest int64 so that it remains valid once converted to a signed integer.
+// Any value greater than or equal to UNBOUNDED is considered as UNBOUNDED by the ToscaRange methods.
 const UNBOUNDED uint64 = 9223372036854775807
 
 // ToscaRange is defined in Appendix 2.3
 // The range type can be used to define numeric ranges with a lower and upper boundary. For example, this allows for specifying a range of ports to be opened in a firewall
-type ToscaRange interface{}
+type ToscaRange [2]uint64
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface
+// Unmarshals a list of the form [ lower, upper ] where upper may be the keyword UNBOUNDED
+func (r *ToscaRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var bounds []string
+	if err := unmarshal(&bounds); err != nil {
+		return err
+	}
+	if len(bounds) != 2 {
+		return fmt.Errorf("A range must have a lower and an upper bound, got %v", bounds)
+	}
+	var rng ToscaRange
+	for i, b := range bounds {
+		if b == "UNBOUNDED" {
+			rng[i] = UNBOUNDED
+			continue
+		}
+		v, err := strconv.ParseUint(b, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid range bound %v", b)
+		}
+		rng[i] = bound(v)
+	}
+	if rng[0] > rng[1] {
+		return fmt.Errorf("Invalid range %v: the lower bound is greater than the upper bound", bounds)
+	}
+	*r = rng
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface
+func (r ToscaRange) MarshalYAML() (interface{}, error) {
+	out := make([]interface{}, 2)
+	for i, b := range r {
+		out[i] = b
+		if b >= UNBOUNDED {
+			out[i] = "UNBOUNDED"
+		}
+	}
+	return out, nil
+}
+
+// String returns the range in its TOSCA notation
+func (r ToscaRange) String() string {
+	upper := strconv.FormatUint(r[1], 10)
+	if r[1] >= UNBOUNDED {
+		upper = "UNBOUNDED"
+	}
+	return fmt.Sprintf("[%v, %v]", r[0], upper)
+}
+
+// IsZero returns true if no bound is set
+func (r ToscaRange) IsZero() bool {
+	return r[0] == 0 && r[1] == 0
+}
+
+// Contains returns true if v is between the lower and the upper bounds of the range.
+// A value greater than or equal to UNBOUNDED is only contained by an open range.
+func (r ToscaRange) Contains(v uint64) bool {
+	v = bound(v)
+	return v >= bound(r[0]) && v <= bound(r[1])
+}
+
+// ContainsScalar returns true if the value of the scalar s in its base unit (B, Hz or ns) is contained by the range,
+// whose bounds are then expressed in this unit: [0, 1073741824] contains "512 MiB".
+// It fails if s is not a valid scalar.
+func (r ToscaRange) ContainsScalar(s Scalar) (bool, error) {
+	v, _, err := s.normalize()
+	if err != nil {
+		return false, err
+	}
+	return r.Contains(uint64(v)), nil
+}
+
+// bound returns v, or UNBOUNDED if v is greater than UNBOUNDED
+func bound(v uint64) uint64 {
+	if v > UNBOUNDED {
+		return UNBOUNDED
+	}
+	return v
+}
+
+// Bounded returns the range as a BoundedRange, where an upper bound greater than or equal to UNBOUNDED is open
+func (r ToscaRange) Bounded() BoundedRange {
+	var b BoundedRange
+	for i, v := range r {
+		b[i] = Bound{Value: v, Unbounded: v >= UNBOUNDED}
+	}
+	return b
+}
+
+// Bound is a bound of a BoundedRange: either a number or the keyword UNBOUNDED
+type Bound struct {
+	Value     uint64 // The value of the bound, ignored if the bound is unbounded
+	Unbounded bool   // True if the bound is the keyword UNBOUNDED
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface
+// Unmarshals a non negative integer or the keyword UNBOUNDED
+func (b *Bound) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	if s == "UNBOUNDED" {
+		*b = Bound{Unbounded: true}
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid range bound %v", s)
+	}
+	*b = Bound{Value: v}
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface
+func (b Bound) MarshalYAML() (interface{}, error) {
+	if b.Unbounded {
+		return "UNBOUNDED", nil
+	}
+	return b.Value, nil
+}
+
+// String returns the value of the bound or UNBOUNDED
+func (b Bound) String() string {
+	if b.Unbounded {
+		return "UNBOUNDED"
+	}
+	return strconv.FormatUint(b.Value, 10)
+}
+
+// BoundedRange is a range as defined in Appendix 2.3 whose open upper bound is explicit
+// rather than a sentinel value as in ToscaRange
+type BoundedRange [2]Bound
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface
+// Unmarshals a list of the form [ lower, upper ] where upper may be the keyword UNBOUNDED
+func (r *BoundedRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var bounds []Bound
+	if err := unmarshal(&bounds); err != nil {
+		return err
+	}
+	if len(bounds) != 2 {
+		return fmt.Errorf("A range must have a lower and an upper bound, got %v", bounds)
+	}
+	rng := BoundedRange{bounds[0], bounds[1]}
+	if rng[0].Unbounded {
+		return fmt.Errorf("Invalid range %v: the lower bound cannot be UNBOUNDED", rng)
+	}
+	if !rng[1].Unbounded && rng[0].Value > rng[1].Value {
+		return fmt.Errorf("Invalid range %v: the lower bound is greater than the upper bound", rng)
+	}
+	*r = rng
+	return nil
+}
+
+// String returns the range in its TOSCA notation
+func (r BoundedRange) String() string {
+	return fmt.Sprintf("[%v, %v]", r[0], r[1])
+}
+
+// Contains returns true if v is between the lower and the upper bounds of the range
+func (r BoundedRange) Contains(v uint64) bool {
+	return v >= r[0].Value && (r[1].Unbounded || v <= r[1].Value)
+}
 
 // ToscaList is defined is Appendix 2.4.
 // The list type allows for specifying multiple values for a parameter of property.
@@ -98,40 +273,5 @@ type Size int64
 // Frequency type as described in appendix A 2.6.6
 type Frequency int64
 
-// Scalar type as defined in Appendis 2.6.
-// The scalar unit type can be used to define scalar values along with a unit from the list of recognized units
-// Scalar type may be time.Duration, Size or Frequency
-type Scalar struct {
-	Value float64
-	Unit  string
-}
-
-// UnmarshalYAML implements the yaml.Unmarshaler interface
-// Unmarshals a string of the form "scalar unit" into a Scalar, validating that scalar and unit are valid
-func (s *Scalar) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	var sString string
-	err := unmarshal(&sString)
-	if err != nil {
-		return err
-	}
-	// Check if the s has two fields (one for the value, and the other one for the unit)
-	ss := strings.Fields(sString)
-	if len(ss) > 2 {
-		return fmt.Errorf("Not a TOSCA scalar")
-	}
-	re := regexp.MustCompile("^([0-9.]+)[[:blank:]]*(B|kB|KiB|MB|MiB|GB|GiB|TB|TiB|d|h|m|s|ms|us|ns|Hz|kHz|MHz|GHz)$")
-	res := re.FindStringSubmatch(sString)
-	if err != nil || len(res) != 3 {
-		return fmt.Errorf("Tosca type unkown")
-	}
-	val, err := strconv.ParseFloat(res[1], 64)
-	if err != nil || len(res) != 3 {
-		return fmt.Errorf("Not a number %v", res[1])
-	}
-	(*s).Value = val
-	(*s).Unit = res[2]
-	return nil
-}
-
 // Regex type used in the constraint definition (Appendix A 5.2.1)
 type Regex interface{}
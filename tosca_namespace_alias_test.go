@@ -0,0 +1,139 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"gopkg.in/yaml.v2"
+	"math"
+	"testing"
+)
+
+func TestToscaRangeUnbounded(t *testing.T) {
+	var r ToscaRange
+	if err := yaml.Unmarshal([]byte("[ 1, UNBOUNDED ]"), &r); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []uint64{1, 2, UNBOUNDED - 1, UNBOUNDED, UNBOUNDED + 1, math.MaxUint64} {
+		if !r.Contains(v) {
+			t.Fatalf("%v should contain %v", r, v)
+		}
+	}
+	if r.Contains(0) {
+		t.Fatalf("%v should not contain 0", r)
+	}
+	bounded := ToscaRange{0, 10}
+	for _, v := range []uint64{11, UNBOUNDED, UNBOUNDED + 1, math.MaxUint64} {
+		if bounded.Contains(v) {
+			t.Fatalf("%v should not contain %v", bounded, v)
+		}
+	}
+	// A bound above UNBOUNDED is the open bound
+	if err := yaml.Unmarshal([]byte("[ 0, 18446744073709551615 ]"), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r[1] != UNBOUNDED || r.String() != "[0, UNBOUNDED]" {
+		t.Fatalf("the upper bound should be UNBOUNDED, got %v", r)
+	}
+	if !(ToscaRange{0, math.MaxUint64}).Contains(UNBOUNDED) {
+		t.Fatal("an upper bound above UNBOUNDED should be open")
+	}
+	out, err := yaml.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "- 0\n- UNBOUNDED\n" {
+		t.Fatalf("bad notation %q", out)
+	}
+}
+
+func TestToscaRangeInvalid(t *testing.T) {
+	var r ToscaRange
+	for _, doc := range []string{"[ 1 ]", "[ 2, 1 ]", "[ UNBOUNDED, 1 ]", "[ -1, 2 ]", "[ a, b ]"} {
+		if err := yaml.Unmarshal([]byte(doc), &r); err == nil {
+			t.Fatalf("%v should not be a valid range", doc)
+		}
+	}
+}
+
+func TestBoundedRange(t *testing.T) {
+	var open BoundedRange
+	if err := yaml.Unmarshal([]byte("[ 1, UNBOUNDED ]"), &open); err != nil {
+		t.Fatal(err)
+	}
+	if !open[1].Unbounded || open.String() != "[1, UNBOUNDED]" {
+		t.Fatalf("the upper bound should be UNBOUNDED, got %v", open)
+	}
+	for _, v := range []uint64{1, UNBOUNDED, math.MaxUint64} {
+		if !open.Contains(v) {
+			t.Errorf("%v should contain %v", open, v)
+		}
+	}
+	if open.Contains(0) {
+		t.Errorf("%v should not contain 0", open)
+	}
+	var closed BoundedRange
+	if err := yaml.Unmarshal([]byte("[ 0, 18446744073709551615 ]"), &closed); err != nil {
+		t.Fatal(err)
+	}
+	if closed[1].Unbounded || !closed.Contains(math.MaxUint64) {
+		t.Errorf("the upper bound %v should be concrete and contained", closed[1])
+	}
+	if (BoundedRange{{Value: 0}, {Value: 10}}).Contains(11) {
+		t.Error("[0, 10] should not contain 11")
+	}
+	out, err := yaml.Marshal(open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "- 1\n- UNBOUNDED\n" {
+		t.Fatalf("bad notation %q", out)
+	}
+	if (ToscaRange{0, UNBOUNDED}).Bounded() != (BoundedRange{{Value: 0}, {Value: UNBOUNDED, Unbounded: true}}) {
+		t.Error("the UNBOUNDED sentinel should be converted to an open bound")
+	}
+	for _, doc := range []string{"[ 1 ]", "[ 2, 1 ]", "[ UNBOUNDED, 1 ]", "[ -1, 2 ]", "[ a, b ]"} {
+		if err := yaml.Unmarshal([]byte(doc), &closed); err == nil {
+			t.Errorf("%v should not be a valid range", doc)
+		}
+	}
+}
+
+func TestToscaRangeContainsScalar(t *testing.T) {
+	r := ToscaRange{1048576, 1073741824}
+	for s, expected := range map[Scalar]bool{
+		"512 MiB":      true,
+		"1 GiB":        true,
+		"1 MiB":        true,
+		"1 GB":         true,
+		"1073741825 B": false,
+		"1 kB":         false,
+		"2 GiB":        false,
+	} {
+		ok, err := r.ContainsScalar(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != expected {
+			t.Errorf("%v contains %v: expected %v", r, s, expected)
+		}
+	}
+	if ok, err := (ToscaRange{0, UNBOUNDED}).ContainsScalar("1 PB"); !ok || err != nil {
+		t.Errorf("an open range should contain 1 PB, got %v (%v)", ok, err)
+	}
+	if _, err := r.ContainsScalar("1 parsec"); err == nil {
+		t.Error("an invalid scalar should not be compared")
+	}
+}
@@ -15,6 +15,10 @@ limitations under the License.
 */
 package toscalib
 
+import (
+	"fmt"
+)
+
 // Output is the output of the topology
 type Output struct {
 	Value       map[string]interface{} `yaml:"value" json:"value"`
@@ -24,9 +28,26 @@ type Output struct {
 // ArtifactDefinition TODO: Appendix 5.5
 type ArtifactDefinition map[string]interface{}
 
-// NodeFilter TODO Appendix 5.4
-// A node filter definition defines criteria for selection of a TOSCA Node Template based upon the template’s property values, capabilities and capability properties.
-type NodeFilter interface{}
+// UnmarshalYAML accepts the short notation of an artifact definition, the path of its file
+func (a *ArtifactDefinition) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var file string
+	if err := unmarshal(&file); err == nil {
+		*a = ArtifactDefinition{"file": file}
+		return nil
+	}
+	var m map[string]interface{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	*a = ArtifactDefinition(m)
+	return nil
+}
+
+// File returns the path of the artifact file
+func (a ArtifactDefinition) File() string {
+	file, _ := a["file"].(string)
+	return file
+}
 
 // DataType as described in Appendix 6.5
 // A Data Type definition defines the schema for new named datatypes in TOSCA.
@@ -47,5 +68,92 @@ type RepositoryDefinition struct {
 
 // ArtifactType as described in appendix 6.3
 //An Artifact Type is a reusable entity that defines the type of one or more files which Node Types or Node Templates can have dependent relationships and used during operations such as during installation or deployment.
-// TODO
-type ArtifactType interface{}
+type ArtifactType struct {
+	DerivedFrom string                        `yaml:"derived_from,omitempty" json:"derived_from,omitempty"` // An optional parent Artifact Type name the Artifact Type derives from.
+	Version     Version                       `yaml:"version,omitempty" json:"version,omitempty"`           // An optional version for the Artifact Type definition.
+	Description string                        `yaml:"description,omitempty" json:"description,omitempty"`   // An optional description for the Artifact Type.
+	MimeType    string                        `yaml:"mime_type,omitempty" json:"mime_type,omitempty"`       // The required mime type property for the Artifact Type.
+	FileExt     []string                      `yaml:"file_ext,omitempty" json:"file_ext,omitempty"`         // The required file extension property for the Artifact Type.
+	Properties  map[string]PropertyDefinition `yaml:"properties,omitempty" json:"properties,omitempty"`     // An optional list of property definitions for the Artifact Type.
+}
+
+// Import is an import definition as described in Appendix 5.3.
+// An import definition is used within a TOSCA Service Template to locate and uniquely name another TOSCA Service Template file which has type and template definitions to be imported (included) and referenced within another Service Template.
+// It may be written as a single file name, as a single-key map of a name to a file name,
+// or as an extended definition (optionally named); the notation used is kept for marshaling.
+type Import struct {
+	Name            string `yaml:"-" json:"name,omitempty"`                                      // The optional name of the import (single-key map notation)
+	File            string `yaml:"file" json:"file"`                                             // The required symbolic name of the file to be imported
+	Repository      string `yaml:"repository,omitempty" json:"repository,omitempty"`             // The optional symbolic name of the repository definition where the imported file can be found
+	NamespaceURI    string `yaml:"namespace_uri,omitempty" json:"namespace_uri,omitempty"`       // The optional namespace URI to that will be applied to type definitions found within the imported file
+	NamespacePrefix string `yaml:"namespace_prefix,omitempty" json:"namespace_prefix,omitempty"` // The optional namespace prefix (alias) that will be used to indicate the namespace_uri when forming a qualified name
+	Extended        bool   `yaml:"-" json:"-"`                                                   // True if the import is written with the extended notation
+}
+
+// importDefinition is the extended notation of an import
+type importDefinition struct {
+	File            string `yaml:"file"`
+	Repository      string `yaml:"repository,omitempty"`
+	NamespaceURI    string `yaml:"namespace_uri,omitempty"`
+	NamespacePrefix string `yaml:"namespace_prefix,omitempty"`
+}
+
+// UnmarshalYAML is used to match the single-line, the single-key map and the extended notations
+func (i *Import) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*i = Import{File: s}
+		return nil
+	}
+	var named map[string]string
+	if err := unmarshal(&named); err == nil && len(named) == 1 {
+		for name, file := range named {
+			switch name {
+			case "file", "repository", "namespace_uri", "namespace_prefix":
+			default:
+				*i = Import{Name: name, File: file}
+				return nil
+			}
+		}
+	}
+	var namedDef map[string]importDefinition
+	if err := unmarshal(&namedDef); err == nil && len(namedDef) == 1 {
+		for name, def := range namedDef {
+			if def.File != "" {
+				*i = Import{Name: name, File: def.File, Repository: def.Repository, NamespaceURI: def.NamespaceURI, NamespacePrefix: def.NamespacePrefix, Extended: true}
+				return nil
+			}
+		}
+	}
+	var def importDefinition
+	if err := unmarshal(&def); err != nil {
+		return err
+	}
+	if def.File == "" {
+		var res interface{}
+		unmarshal(&res)
+		return fmt.Errorf("Cannot parse Import %v", res)
+	}
+	*i = Import{File: def.File, Repository: def.Repository, NamespaceURI: def.NamespaceURI, NamespacePrefix: def.NamespacePrefix, Extended: true}
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface, the import is written with the notation it has been read with
+func (i Import) MarshalYAML() (interface{}, error) {
+	def := importDefinition{
+		File:            i.File,
+		Repository:      i.Repository,
+		NamespaceURI:    i.NamespaceURI,
+		NamespacePrefix: i.NamespacePrefix,
+	}
+	switch {
+	case !i.Extended && i.Name == "":
+		return i.File, nil
+	case !i.Extended:
+		return map[string]string{i.Name: i.File}, nil
+	case i.Name == "":
+		return def, nil
+	default:
+		return map[string]importDefinition{i.Name: def}, nil
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"gopkg.in/yaml.v2"
+	"testing"
+)
+
+func TestImportRoundTrip(t *testing.T) {
+	tests := map[string]Import{
+		"- some_definitions.yaml\n":                                                        {File: "some_definitions.yaml"},
+		"- my_types: my_types.yaml\n":                                                      {Name: "my_types", File: "my_types.yaml"},
+		"- file: types.yaml\n  repository: my_repo\n  namespace_prefix: mt\n":              {File: "types.yaml", Repository: "my_repo", NamespacePrefix: "mt", Extended: true},
+		"- my_types:\n    file: types.yaml\n    namespace_uri: http://example.com/types\n": {Name: "my_types", File: "types.yaml", NamespaceURI: "http://example.com/types", Extended: true},
+	}
+	for doc, expected := range tests {
+		var imports []Import
+		if err := yaml.Unmarshal([]byte(doc), &imports); err != nil {
+			t.Fatalf("%q: %v", doc, err)
+		}
+		if len(imports) != 1 || imports[0] != expected {
+			t.Fatalf("%q: parsed %+v, expected %+v", doc, imports, expected)
+		}
+		out, err := yaml.Marshal(imports)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != doc {
+			t.Fatalf("%q re-emitted as %q", doc, out)
+		}
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	var imports []Import
+	if err := yaml.Unmarshal([]byte("- repository: my_repo\n"), &imports); err == nil {
+		t.Fatal("an import without file should not be valid")
+	}
+}
@@ -0,0 +1,744 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ValidationError is an error reported by Validate.
+// Line is the line of the YAML source where the error is located, 0 if it is unknown.
+type ValidationError struct {
+	Line int
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("line %v: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// errorAt returns a ValidationError located at the YAML node path
+func (s *ServiceTemplateDefinition) errorAt(err error, path ...string) error {
+	return &ValidationError{Line: s.line(path...), Err: err}
+}
+
+// Validate checks the service template for the errors that can be detected
+// without deploying it. It returns all the errors found, nil if the template is valid.
+func (s *ServiceTemplateDefinition) Validate() []error {
+	var errs []error
+	for _, check := range []func() []error{
+		s.validateOccurrences,
+		s.validateProperties,
+		s.validateCapabilityProperties,
+		s.validateCapabilityAttributes,
+		s.validateRequiredCapabilities,
+		s.validateInterfaceTypes,
+		s.validateRequirementForms,
+		s.validateRequirementTargets,
+		s.validateSubstitutionMappings,
+		s.validateDefaults,
+		s.validateEntrySchemas,
+		s.validatePropertyTypes,
+		s.validateOutputs,
+		s.validateDirectives,
+		s.validatePolicies,
+		s.validateWorkflows,
+		s.validateArtifacts,
+	} {
+		errs = append(errs, check()...)
+	}
+	return errs
+}
+
+// nodeNames returns the sorted names of the node templates
+func (s *ServiceTemplateDefinition) nodeNames() []string {
+	names := make([]string, 0, len(s.TopologyTemplate.NodeTemplates))
+	for name := range s.TopologyTemplate.NodeTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateOccurrences checks that the number of assignments of each requirement
+// of the node templates is within the occurrences declared by their node type,
+// [1, 1] if the definition omits them: such a requirement must be assigned exactly once.
+func (s *ServiceTemplateDefinition) validateOccurrences() []error {
+	var errs []error
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		nt, err := s.FlattenNodeType(node.Type)
+		if err != nil {
+			errs = append(errs, s.errorAt(fmt.Errorf("Node %v: %v", name, err), "topology_template", "node_templates", name, "type"))
+			continue
+		}
+		count := make(map[string]uint64)
+		for _, req := range node.Requirements {
+			for reqName := range req {
+				count[reqName]++
+			}
+		}
+		for _, req := range nt.Requirements {
+			for reqName, def := range req {
+				occurrences := def.Occurrences
+				if occurrences.IsZero() {
+					// A definition that was not parsed, such as one built in code
+					occurrences = defaultOccurrences
+				}
+				if !occurrences.Contains(count[reqName]) {
+					err := fmt.Errorf("Node %v: requirement %v: expected %v assignments, got %v", name, reqName, occurrences, count[reqName])
+					errs = append(errs, s.errorAt(err, "topology_template", "node_templates", name))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateProperties checks that the properties assigned by the node templates
+// are defined by their node type, and that the scalar-unit values have a unit of the declared kind
+func (s *ServiceTemplateDefinition) validateProperties() []error {
+	var errs []error
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		nt, err := s.FlattenNodeType(node.Type)
+		if err != nil {
+			// Already reported by validateOccurrences
+			continue
+		}
+		props := sortedPropertyNames(node.Properties)
+		for _, prop := range props {
+			def, ok := nt.Properties[prop]
+			if !ok {
+				err := fmt.Errorf("Node %v: property %v is not defined by %v", name, prop, node.Type)
+				errs = append(errs, s.errorAt(err, "topology_template", "node_templates", name, "properties", prop))
+				continue
+			}
+			kind := ScalarKind(def.Type)
+			v, literal := node.Properties[prop]["value"]
+			if _, isScalar := baseUnits[kind]; !isScalar || !literal || len(v) != 1 || v[0] == nil {
+				continue
+			}
+			if err := checkKind(v[0], kind); err != nil {
+				err = fmt.Errorf("Node %v: property %v: %v", name, prop, err)
+				errs = append(errs, s.errorAt(err, "topology_template", "node_templates", name, "properties", prop))
+			}
+		}
+	}
+	return errs
+}
+
+// validateCapabilityProperties checks the properties assigned to the capabilities of the node templates
+// against the property definitions of the capability types
+func (s *ServiceTemplateDefinition) validateCapabilityProperties() []error {
+	var errs []error
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		nt, err := s.FlattenNodeType(node.Type)
+		if err != nil {
+			// Already reported by validateOccurrences
+			continue
+		}
+		for _, capName := range sortedKeys(node.Capabilities) {
+			path := []string{"topology_template", "node_templates", name, "capabilities", capName}
+			capDef, ok := nt.Capabilities[capName]
+			if !ok {
+				errs = append(errs, s.errorAt(fmt.Errorf("Node %v: capability %v is not defined by %v", name, capName, node.Type), path...))
+				continue
+			}
+			ct, err := s.CapabilityType(capDef.Type)
+			if err != nil {
+				errs = append(errs, s.errorAt(fmt.Errorf("Node %v: capability %v: %v", name, capName, err), path...))
+				continue
+			}
+			assignment, _ := node.Capabilities[capName].(map[interface{}]interface{})
+			props, _ := assignment["properties"].(map[interface{}]interface{})
+			values := make(map[string]interface{}, len(props))
+			for k, v := range props {
+				values[fmt.Sprint(k)] = v
+			}
+			names := sortedKeys(values)
+			for _, prop := range names {
+				def, ok := ct.Properties[prop]
+				if !ok {
+					err = fmt.Errorf("Node %v: capability %v: property %v is not defined by %v", name, capName, prop, capDef.Type)
+				} else if _, isFunction := values[prop].(map[interface{}]interface{}); isFunction {
+					continue
+				} else if err = def.check(values[prop]); err != nil {
+					err = fmt.Errorf("Node %v: capability %v: property %v: %v", name, capName, prop, err)
+				}
+				if err != nil {
+					errs = append(errs, s.errorAt(err, append(path, "properties", prop)...))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateRequiredCapabilities checks that the node templates provide the capabilities
+// whose occurrences declared by their node type have a lower bound of at least 1:
+// a template that omits such a capability or assigns it a null value is reported.
+func (s *ServiceTemplateDefinition) validateRequiredCapabilities() []error {
+	var errs []error
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		nt, err := s.FlattenNodeType(node.Type)
+		if err != nil {
+			// Already reported by validateOccurrences
+			continue
+		}
+		required := make([]string, 0, len(nt.Capabilities))
+		for capName, capDef := range nt.Capabilities {
+			if capDef.occurrencesDeclared && capDef.Occurrences[0] > 0 {
+				required = append(required, capName)
+			}
+		}
+		sort.Strings(required)
+		for _, capName := range required {
+			path := []string{"topology_template", "node_templates", name}
+			assignment, ok := node.Capabilities[capName]
+			switch {
+			case !ok:
+				errs = append(errs, s.errorAt(fmt.Errorf("Node %v: the capability %v required by %v is missing", name, capName, node.Type), path...))
+			case assignment == nil:
+				errs = append(errs, s.errorAt(fmt.Errorf("Node %v: the capability %v required by %v is suppressed", name, capName, node.Type), append(path, "capabilities", capName)...))
+			}
+		}
+	}
+	return errs
+}
+
+// validateCapabilityAttributes checks the default values of the attributes of the capability types
+// and the attributes assigned to the capabilities of the node templates
+func (s *ServiceTemplateDefinition) validateCapabilityAttributes() []error {
+	var errs []error
+	types := make([]string, 0, len(s.CapabilityTypes))
+	for name := range s.CapabilityTypes {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	for _, name := range types {
+		attrs := s.CapabilityTypes[name].Attributes
+		names := make([]string, 0, len(attrs))
+		for attr := range attrs {
+			names = append(names, attr)
+		}
+		sort.Strings(names)
+		for _, attr := range names {
+			def := attrs[attr]
+			if def.Default == nil {
+				continue
+			}
+			if err := (PropertyDefinition{Type: def.Type}).check(def.Default); err != nil {
+				err = fmt.Errorf("Capability type %v: attribute %v: invalid default %v: %v", name, attr, def.Default, err)
+				errs = append(errs, s.errorAt(err, "capability_types", name, "attributes", attr, "default"))
+			}
+		}
+	}
+	for _, name := range s.nodeNames() {
+		node := s.TopologyTemplate.NodeTemplates[name]
+		nt, err := s.FlattenNodeType(node.Type)
+		if err != nil {
+			// Already reported by validateOccurrences
+			continue
+		}
+		for _, capName := range sortedKeys(node.Capabilities) {
+			assignment, _ := node.Capabilities[capName].(map[interface{}]interface{})
+			values, _ := assignment["attributes"].(map[interface{}]interface{})
+			capDef, ok := nt.Capabilities[capName]
+			if !ok || len(values) == 0 {
+				// An undefined capability is reported by validateCapabilityProperties
+				continue
+			}
+			attrs, err := s.capabilityAttributes(capDef)
+			if err != nil {
+				continue
+			}
+			names := make([]string, 0, len(values))
+			for k := range values {
+				names = append(names, fmt.Sprint(k))
+			}
+			sort.Strings(names)
+			path := []string{"topology_template", "node_templates", name, "capabilities", capName, "attributes"}
+			for _, attr := range names {
+				v := values[attr]
+				def, ok := attrs[attr]
+				if !ok {
+					err = fmt.Errorf("Node %v: capability %v: attribute %v is not defined by %v", name, capName, attr, capDef.Type)
+				} else if _, isFunction := v.(map[interface{}]interface{}); isFunction {
+					continue
+				} else if err = (PropertyDefinition{Type: def.Type}).check(v); err != nil {
+					err = fmt.Errorf("Node %v: capability %v: attribute %v: %v", name, capName, attr, err)
+				}
+				if err != nil {
+					errs = append(errs, s.errorAt(err, append(path, attr)...))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// sortedKeys returns the sorted keys of m
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateInterfaceTypes checks that the interfaces of the node and relationship types reference known interface types
+func (s *ServiceTemplateDefinition) validateInterfaceTypes() []error {
+	var errs []error
+	check := func(section, name string, interfaces map[string]InterfaceDefinition) {
+		intfNames := make([]string, 0, len(interfaces))
+		for intfName := range interfaces {
+			intfNames = append(intfNames, intfName)
+		}
+		sort.Strings(intfNames)
+		for _, intfName := range intfNames {
+			t := interfaces[intfName].Type
+			if t == "" {
+				continue
+			}
+			if _, err := s.InterfaceType(t); err != nil {
+				err = fmt.Errorf("Type %v: interface %v: %v", name, intfName, err)
+				errs = append(errs, s.errorAt(err, section, name, "interfaces", intfName, "type"))
+			}
+		}
+	}
+	names := make([]string, 0, len(s.NodeTypes))
+	for name := range s.NodeTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check("node_types", name, s.NodeTypes[name].Interfaces)
+	}
+	names = names[:0]
+	for name := range s.RelationshipTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check("relationship_types", name, s.RelationshipTypes[name].Interfaces)
+	}
+	return errs
+}
+
+// validateRequirementForms checks that the requirement assignments do not give both
+// a node template and a node filter to select their target
+func (s *ServiceTemplateDefinition) validateRequirementForms() []error {
+	var errs []error
+	for _, name := range s.nodeNames() {
+		for i, req := range s.TopologyTemplate.NodeTemplates[name].Requirements {
+			for reqName, ra := range req {
+				if _, ok := s.TopologyTemplate.NodeTemplates[ra.Node]; !ok || ra.Nodefilter.isEmpty() {
+					continue
+				}
+				err := fmt.Errorf("Node %v: requirement %v: both the node template %v and a node filter are given", name, reqName, ra.Node)
+				errs = append(errs, s.errorAt(err, "topology_template", "node_templates", name, "requirements", strconv.Itoa(i), reqName))
+			}
+		}
+	}
+	return errs
+}
+
+// validateRequirementTargets checks that the target node template, the capability and the relationship
+// of each requirement assignment of the node templates exist, so that no requirement is left dangling
+func (s *ServiceTemplateDefinition) validateRequirementTargets() []error {
+	var errs []error
+	for _, name := range s.nodeNames() {
+		nt := s.GetNodeTemplate(name)
+		sourceType, err := s.FlattenNodeType(nt.Type)
+		if err != nil {
+			// Already reported by validateOccurrences
+			continue
+		}
+		for i, req := range nt.Requirements {
+			for reqName, ra := range req {
+				if _, _, err := s.matchRequirement(nt, sourceType, reqName, ra); err != nil {
+					errs = append(errs, s.errorAt(err, "topology_template", "node_templates", name, "requirements", strconv.Itoa(i), reqName))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateSubstitutionMappings checks that the property and attribute mappings of the substitution
+// mappings reference a property or an attribute of a node template, or an input of the topology
+func (s *ServiceTemplateDefinition) validateSubstitutionMappings() []error {
+	var errs []error
+	sm := s.TopologyTemplate.SubstitutionMappings
+	check := func(section string, mappings map[string][]string, defined func(NodeType, string) bool) {
+		names := make([]string, 0, len(mappings))
+		for name := range mappings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var err error
+			switch m := mappings[name]; {
+			case len(m) == 1 && section == "properties":
+				if _, ok := s.TopologyTemplate.Inputs[m[0]]; !ok {
+					err = fmt.Errorf("input %v not found", m[0])
+				}
+			case len(m) != 2:
+				err = fmt.Errorf("invalid mapping %v", m)
+			default:
+				node, ok := s.TopologyTemplate.NodeTemplates[m[0]]
+				if !ok {
+					err = fmt.Errorf("node %v not found", m[0])
+					break
+				}
+				nt, ferr := s.FlattenNodeType(node.Type)
+				if ferr != nil {
+					err = ferr
+				} else if !defined(nt, m[1]) {
+					err = fmt.Errorf("%v is not defined by node %v", m[1], m[0])
+				}
+			}
+			if err != nil {
+				err = fmt.Errorf("Substitution mappings: %v %v: %v", section, name, err)
+				errs = append(errs, s.errorAt(err, "topology_template", "substitution_mappings", section, name))
+			}
+		}
+	}
+	check("properties", sm.Properties, func(nt NodeType, name string) bool {
+		_, ok := nt.Properties[name]
+		return ok
+	})
+	check("attributes", sm.Attributes, func(nt NodeType, name string) bool {
+		_, ok := nt.Attributes[name]
+		return ok
+	})
+	return errs
+}
+
+// validateDefaults checks that the default value of each property definition of the types
+// and of each input of the topology matches its type and constraints
+func (s *ServiceTemplateDefinition) validateDefaults() []error {
+	var errs []error
+	s.walkPropertyDefinitions(func(label string, def PropertyDefinition, path []string) {
+		if !def.HasDefault() {
+			return
+		}
+		if err := def.check(def.Default); err != nil {
+			err = fmt.Errorf("%v: invalid default %v: %v", label, def.Default, err)
+			errs = append(errs, s.errorAt(err, append(path, "default")...))
+		}
+	})
+	return errs
+}
+
+// walkPropertyDefinitions calls fn with each property definition of the node, relationship, capability
+// and data types, then with each input definition. label names the definition, such as
+// "Node type my.nodes.App: property port", and path locates it in the document.
+func (s *ServiceTemplateDefinition) walkPropertyDefinitions(fn func(label string, def PropertyDefinition, path []string)) {
+	walk := func(label string, props map[string]PropertyDefinition, path ...string) {
+		names := sortedPropertyDefinitions(props)
+		for _, name := range names {
+			fn(fmt.Sprintf("%v %v", label, name), props[name], append(append([]string{}, path...), name))
+		}
+	}
+	sections := []struct {
+		section string
+		kind    string
+		props   map[string]map[string]PropertyDefinition
+	}{
+		{"node_types", "Node type", make(map[string]map[string]PropertyDefinition)},
+		{"relationship_types", "Relationship type", make(map[string]map[string]PropertyDefinition)},
+		{"capability_types", "Capability type", make(map[string]map[string]PropertyDefinition)},
+		{"data_types", "Data type", make(map[string]map[string]PropertyDefinition)},
+	}
+	for name, t := range s.NodeTypes {
+		sections[0].props[name] = t.Properties
+	}
+	for name, t := range s.RelationshipTypes {
+		sections[1].props[name] = t.Properties
+	}
+	for name, t := range s.CapabilityTypes {
+		sections[2].props[name] = t.Properties
+	}
+	for name, t := range s.DataTypes {
+		sections[3].props[name] = t.Properties
+	}
+	for _, sec := range sections {
+		names := make([]string, 0, len(sec.props))
+		for name := range sec.props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			walk(fmt.Sprintf("%v %v: property", sec.kind, name), sec.props[name], sec.section, name, "properties")
+		}
+	}
+	walk("Input", s.TopologyTemplate.Inputs, "topology_template", "inputs")
+}
+
+// validateEntrySchemas checks that the list and map properties of the types and the list and map inputs
+// declare the schema of their entries
+func (s *ServiceTemplateDefinition) validateEntrySchemas() []error {
+	var errs []error
+	s.walkPropertyDefinitions(func(label string, def PropertyDefinition, path []string) {
+		if def.Type != "list" && def.Type != "map" {
+			return
+		}
+		if schema, ok := def.EntrySchema.(map[string]interface{}); def.EntrySchema == nil || ok && len(schema) == 0 {
+			errs = append(errs, s.errorAt(fmt.Errorf("%v: the %v has no entry_schema", label, def.Type), path...))
+		}
+	})
+	return errs
+}
+
+// validatePropertyTypes checks that the node types do not redefine an inherited property with an incompatible type.
+// A property may only be redefined with the same type or with a data type derived from it.
+func (s *ServiceTemplateDefinition) validatePropertyTypes() []error {
+	var errs []error
+	names := make([]string, 0, len(s.NodeTypes))
+	for name := range s.NodeTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		nt := s.NodeTypes[name]
+		for _, prop := range sortedPropertyDefinitions(nt.Properties) {
+			typ := nt.Properties[prop].Type
+			if typ == "" {
+				continue
+			}
+			parent, inherited := s.inheritedProperty(nt.DerivedFrom, prop)
+			if inherited.Type == "" || s.isDataType(typ, inherited.Type) {
+				continue
+			}
+			err := fmt.Errorf("Node type %v: property %v: the type %v conflicts with the type %v inherited from %v", name, prop, typ, inherited.Type, parent)
+			errs = append(errs, s.errorAt(err, "node_types", name, "properties", prop, "type"))
+		}
+	}
+	return errs
+}
+
+// inheritedProperty returns the definition of the property prop by the closest node type
+// of the derivation chain starting at name, and the name of that node type
+func (s *ServiceTemplateDefinition) inheritedProperty(name, prop string) (string, PropertyDefinition) {
+	visited := make(map[string]bool)
+	for n := name; n != "" && !visited[n]; {
+		visited[n] = true
+		nt, ok := s.NodeTypes[n]
+		if !ok {
+			break
+		}
+		if def, ok := nt.Properties[prop]; ok {
+			return n, def
+		}
+		n = nt.DerivedFrom
+	}
+	return "", PropertyDefinition{}
+}
+
+// isDataType returns true if the data type name is ancestor or derives from it
+func (s *ServiceTemplateDefinition) isDataType(name, ancestor string) bool {
+	return derivesFrom(name, ancestor, func(n string) (string, bool) {
+		dt, ok := s.DataTypes[n]
+		return dt.DerivedFrom, ok
+	})
+}
+
+// sortedPropertyDefinitions returns the sorted names of the property definitions props
+func sortedPropertyDefinitions(props map[string]PropertyDefinition) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateOutputs checks that the get_attribute functions of the outputs reference an existing node template
+// and an attribute declared by its node type, or by one of its capabilities when the function names one.
+// The properties are accepted as attributes since an attribute is implicitly created for each property.
+func (s *ServiceTemplateDefinition) validateOutputs() []error {
+	var errs []error
+	names := make([]string, 0, len(s.TopologyTemplate.Outputs))
+	for name := range s.TopologyTemplate.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var bad []error
+		walkFunctions(s.TopologyTemplate.Outputs[name].Value, func(fn string, args []interface{}) {
+			if fn != "get_attribute" {
+				return
+			}
+			if err := s.checkAttributeReference(args); err != nil {
+				bad = append(bad, fmt.Errorf("Output %v: get_attribute %v: %v", name, args, err))
+			}
+		})
+		for _, err := range bad {
+			errs = append(errs, s.errorAt(err, "topology_template", "outputs", name, "value"))
+		}
+	}
+	return errs
+}
+
+// checkAttributeReference checks the arguments of a get_attribute function:
+// [ node, attribute ] or [ node, capability, attribute ]
+func (s *ServiceTemplateDefinition) checkAttributeReference(args []interface{}) error {
+	if len(args) < 2 {
+		return fmt.Errorf("expected a node and an attribute")
+	}
+	node, ok := s.TopologyTemplate.NodeTemplates[fmt.Sprint(args[0])]
+	if !ok {
+		return fmt.Errorf("node %v not found", args[0])
+	}
+	nt, err := s.FlattenNodeType(node.Type)
+	if err != nil {
+		return err
+	}
+	attr := fmt.Sprint(args[1])
+	if capDef, ok := nt.Capabilities[attr]; ok && len(args) > 2 {
+		ct, err := s.CapabilityType(capDef.Type)
+		if err != nil {
+			return err
+		}
+		attrs, err := s.capabilityAttributes(capDef)
+		if err != nil {
+			return err
+		}
+		attr = fmt.Sprint(args[2])
+		_, isAttr := attrs[attr]
+		_, isProp := ct.Properties[attr]
+		if !isAttr && !isProp {
+			return fmt.Errorf("attribute %v is not defined by capability %v of node %v", attr, args[1], args[0])
+		}
+		return nil
+	}
+	_, isAttr := nt.Attributes[attr]
+	_, isProp := nt.Properties[attr]
+	if !isAttr && !isProp {
+		return fmt.Errorf("attribute %v is not defined by node %v", attr, args[0])
+	}
+	return nil
+}
+
+// directives are the known directives of the node templates, from TOSCA 1.0 and 1.3
+var directives = map[string]bool{
+	"selectable":    true,
+	"substitutable": true,
+	"select":        true,
+	"substitute":    true,
+}
+
+// validateDirectives checks that the node templates only use known directives
+func (s *ServiceTemplateDefinition) validateDirectives() []error {
+	var errs []error
+	for _, name := range s.nodeNames() {
+		for i, d := range s.TopologyTemplate.NodeTemplates[name].Directives {
+			if !directives[d] {
+				err := fmt.Errorf("Node %v: unknown directive %v", name, d)
+				errs = append(errs, s.errorAt(err, "topology_template", "node_templates", name, "directives", strconv.Itoa(i)))
+			}
+		}
+	}
+	return errs
+}
+
+// validatePolicies checks that the policies target existing node templates and that their triggers
+// reference defined attributes, operations and workflows
+func (s *ServiceTemplateDefinition) validatePolicies() []error {
+	var errs []error
+	for i, policies := range s.TopologyTemplate.Policies {
+		for name, policy := range policies {
+			path := []string{"topology_template", "policies", strconv.Itoa(i), name}
+			for j, target := range policy.Targets {
+				if _, ok := s.TopologyTemplate.NodeTemplates[target]; !ok {
+					err := fmt.Errorf("Policy %v: target %v not found", name, target)
+					errs = append(errs, s.errorAt(err, append(path, "targets", strconv.Itoa(j))...))
+				}
+			}
+			triggers := make([]string, 0, len(policy.Triggers))
+			for trigger := range policy.Triggers {
+				triggers = append(triggers, trigger)
+			}
+			sort.Strings(triggers)
+			for _, trigger := range triggers {
+				condition, action := s.checkTrigger(policy.Triggers[trigger], policy.Targets)
+				for _, err := range condition {
+					err = fmt.Errorf("Policy %v: trigger %v: condition: %v", name, trigger, err)
+					errs = append(errs, s.errorAt(err, append(path, "triggers", trigger, "condition")...))
+				}
+				for _, err := range action {
+					err = fmt.Errorf("Policy %v: trigger %v: action: %v", name, trigger, err)
+					errs = append(errs, s.errorAt(err, append(path, "triggers", trigger, "action")...))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateWorkflows checks that the transitions of the steps of the workflows reference existing steps
+// and are acyclic
+func (s *ServiceTemplateDefinition) validateWorkflows() []error {
+	var errs []error
+	names := make([]string, 0, len(s.TopologyTemplate.Workflows))
+	for name := range s.TopologyTemplate.Workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := s.TopologyTemplate.Workflows[name].StepGraph(); err != nil {
+			err = fmt.Errorf("Workflow %v: %v", name, err)
+			errs = append(errs, s.errorAt(err, "topology_template", "workflows", name, "steps"))
+		}
+	}
+	return errs
+}
+
+// validateArtifacts checks that the artifacts of the node templates are of a known artifact type
+// and that the extensions of their files match their type
+func (s *ServiceTemplateDefinition) validateArtifacts() []error {
+	var errs []error
+	for _, name := range s.nodeNames() {
+		artifacts := s.TopologyTemplate.NodeTemplates[name].Artifcats
+		names := make([]string, 0, len(artifacts))
+		for artifact := range artifacts {
+			names = append(names, artifact)
+		}
+		sort.Strings(names)
+		for _, artifact := range names {
+			if err := s.checkArtifact(artifacts[artifact]); err != nil {
+				err = fmt.Errorf("Node %v: artifact %v: %v", name, artifact, err)
+				errs = append(errs, s.errorAt(err, "topology_template", "node_templates", name, "artifacts", artifact))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,577 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const occurrencesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Cluster:
+    derived_from: tosca.nodes.Root
+    requirements:
+      - member:
+          capability: tosca.capabilities.Node
+          relationship: tosca.relationships.DependsOn
+          occurrences: [ 2, 2 ]
+topology_template:
+  node_templates:
+    a:
+      type: tosca.nodes.Root
+    b:
+      type: tosca.nodes.Root
+    c:
+      type: tosca.nodes.Root
+    ok:
+      type: my.nodes.Cluster
+      requirements:
+        - member: a
+        - member: b
+    under:
+      type: my.nodes.Cluster
+      requirements:
+        - member: a
+    over:
+      type: my.nodes.Cluster
+      requirements:
+        - member: a
+        - member: b
+        - member: c
+`
+
+// expectErrors fails if errs does not hold exactly one error containing each of the expected strings
+func expectErrors(t *testing.T, errs []error, expected ...[]string) {
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %v errors, got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		for _, e := range expected[i] {
+			if !strings.Contains(err.Error(), e) {
+				t.Fatalf("error %q should contain %q", err, e)
+			}
+		}
+	}
+}
+
+func TestValidateOccurrences(t *testing.T) {
+	s := parseString(t, occurrencesTemplate)
+	expectErrors(t, s.Validate(),
+		[]string{"over", "member", "[2, 2]", "got 3"},
+		[]string{"under", "member", "[2, 2]", "got 1"},
+	)
+}
+
+const badPropertyTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    app:
+      type: tosca.nodes.SoftwareComponent
+      properties:
+        component_version: 1.0
+        colour: blue
+`
+
+func TestValidatePropertyLine(t *testing.T) {
+	s := parseString(t, badPropertyTemplate)
+	errs := s.Validate()
+	// The host requirement of app is left unassigned
+	expectErrors(t, errs,
+		[]string{"app", "requirement host", "[1, 1]", "got 0"},
+		[]string{"app", "colour", "tosca.nodes.SoftwareComponent"},
+	)
+	var verr *ValidationError
+	if !errors.As(errs[1], &verr) {
+		t.Fatalf("expected a ValidationError, got %T", errs[1])
+	}
+	if verr.Line != 10 {
+		t.Fatalf("expected the error at line 10, got %v", verr.Line)
+	}
+	if !strings.HasPrefix(verr.Error(), "line 10: ") {
+		t.Fatalf("the error should start with its line, got %q", verr)
+	}
+}
+
+func TestValidateMisspelledProperty(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.SoftwareComponent
+    properties:
+      context_root:
+        type: string
+topology_template:
+  node_templates:
+    app:
+      type: my.nodes.App
+      properties:
+        context_root: /app
+        component_verison: 1.0
+`)
+	expectErrors(t, s.Validate(),
+		[]string{"Node app", "requirement host", "got 0"},
+		[]string{"Node app", "property component_verison", "my.nodes.App"},
+	)
+}
+
+func TestValidateDefaults(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Cache:
+    derived_from: tosca.nodes.SoftwareComponent
+    properties:
+      size:
+        type: scalar-unit.size
+        default: 64 GB
+        constraints:
+          - in_range: [ 1 MB, 16 GB ]
+      entries:
+        type: integer
+        default: 1000
+topology_template:
+  inputs:
+    replicas:
+      type: integer
+      default: three
+  node_templates:
+    cache:
+      type: my.nodes.Cache
+`)
+	errs := s.Validate()
+	expectErrors(t, errs,
+		[]string{"Node cache", "requirement host", "got 0"},
+		[]string{"Node type my.nodes.Cache: property size", "invalid default 64 GB"},
+		[]string{"Input replicas", "three is not an integer"},
+	)
+	var verr *ValidationError
+	if !errors.As(errs[1], &verr) || verr.Line != 8 {
+		t.Fatalf("expected the error at line 8, got %v", errs[1])
+	}
+}
+
+func TestValidatePropertyTypes(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+data_types:
+  my.datatypes.Endpoint:
+    derived_from: tosca.datatypes.Root
+  my.datatypes.SecureEndpoint:
+    derived_from: my.datatypes.Endpoint
+node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.SoftwareComponent
+    properties:
+      port:
+        type: string
+      endpoint:
+        type: my.datatypes.Endpoint
+  my.nodes.WebApp:
+    derived_from: my.nodes.App
+    properties:
+      endpoint:
+        type: my.datatypes.SecureEndpoint
+  my.nodes.Api:
+    derived_from: my.nodes.WebApp
+    properties:
+      port:
+        type: integer
+topology_template:
+  node_templates:
+`)
+	errs := s.Validate()
+	expectErrors(t, errs, []string{"Node type my.nodes.Api", "property port", "integer conflicts with the type string inherited from my.nodes.App"})
+	var verr *ValidationError
+	if !errors.As(errs[0], &verr) || verr.Line != 24 {
+		t.Fatalf("the error should be located at line 24, got %v", errs[0])
+	}
+}
+
+func TestValidateScalarKinds(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Server:
+    derived_from: tosca.nodes.Root
+    properties:
+      clock:
+        type: scalar-unit.frequency
+        default: 4 GB
+      memory:
+        type: scalar-unit.size
+        default: 30 s
+      timeout:
+        type: scalar-unit.time
+        default: 2 GHz
+topology_template:
+  node_templates:
+    server:
+      type: my.nodes.Server
+      properties:
+        memory: 8 GHz
+`)
+	expectErrors(t, s.Validate(),
+		[]string{"Node server: property memory", "8 GHz is a scalar-unit.frequency, not a scalar-unit.size"},
+		[]string{"Node type my.nodes.Server: property clock", "4 GB is a scalar-unit.size, not a scalar-unit.frequency"},
+		[]string{"Node type my.nodes.Server: property memory", "30 s is a scalar-unit.time, not a scalar-unit.size"},
+		[]string{"Node type my.nodes.Server: property timeout", "2 GHz is a scalar-unit.frequency, not a scalar-unit.time"},
+	)
+}
+
+func TestValidateBareZeroScalar(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            disk_size: 0
+            mem_size: 5
+`)
+	expectErrors(t, s.Validate(), []string{"Node server", "property mem_size", ErrMissingUnit.Error()})
+}
+
+func TestValidateDefaultOccurrences(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.App:
+    derived_from: tosca.nodes.Root
+    requirements:
+      - server: tosca.capabilities.Node
+      - backend:
+          capability: tosca.capabilities.Node
+    capabilities:
+      api: tosca.capabilities.Endpoint
+      admin:
+        type: tosca.capabilities.Endpoint
+topology_template:
+  node_templates:
+    a:
+      type: tosca.nodes.Root
+    b:
+      type: tosca.nodes.Root
+    one:
+      type: my.nodes.App
+      requirements:
+        - server: a
+        - backend: a
+    none:
+      type: my.nodes.App
+      requirements:
+        - backend: a
+    two:
+      type: my.nodes.App
+      requirements:
+        - server: a
+        - backend: a
+        - backend: b
+`)
+	nt, err := s.FlattenNodeType("my.nodes.App")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range nt.Requirements {
+		for name, def := range req {
+			if name != "dependency" && def.Occurrences != (ToscaRange{1, 1}) {
+				t.Errorf("requirement %v: expected the default occurrences [1, 1], got %v", name, def.Occurrences)
+			}
+		}
+	}
+	for _, name := range []string{"api", "admin"} {
+		if occurrences := nt.Capabilities[name].Occurrences; occurrences != (ToscaRange{1, 1}) {
+			t.Errorf("capability %v: expected the default occurrences [1, 1], got %v", name, occurrences)
+		}
+	}
+	expectErrors(t, s.Validate(),
+		[]string{"none", "requirement server", "[1, 1]", "got 0"},
+		[]string{"two", "requirement backend", "[1, 1]", "got 2"},
+	)
+}
+
+func TestValidateRequirementTargets(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  relationship_templates:
+    storage_attachment:
+      type: tosca.relationships.AttachesTo
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+      requirements:
+        - local_storage:
+            node: deleted_volume
+            relationship: storage_attachment
+        - dependency:
+            node: server
+            relationship: missing_relationship
+`)
+	errs := s.Validate()
+	expectErrors(t, errs,
+		[]string{"Node server", "requirement local_storage", "target node deleted_volume not found"},
+		[]string{"Node server", "requirement dependency", "missing_relationship"},
+	)
+	var verr *ValidationError
+	if !errors.As(errs[0], &verr) || verr.Line != 10 {
+		t.Fatalf("the error should be located at line 10, got %v", errs[0])
+	}
+}
+
+func TestValidateOutputs(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+  outputs:
+    address:
+      value: { get_attribute: [ server, public_address ] }
+    cpus:
+      value: { get_attribute: [ server, host, num_cpus ] }
+    url:
+      value: { concat: [ "http://", { get_attribute: [ server, public_adress ] } ] }
+    missing:
+      value: { get_attribute: [ web, tosca_id ] }
+`)
+	expectErrors(t, s.Validate(),
+		[]string{"Output missing", "node web not found"},
+		[]string{"Output url", "attribute public_adress is not defined by node server"},
+	)
+}
+
+func TestValidateDirectives(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    db:
+      type: tosca.nodes.Root
+      directives: [ select ]
+    cache:
+      type: tosca.nodes.Root
+      directives: [ substitute, instantiate ]
+`)
+	if d := s.TopologyTemplate.NodeTemplates["db"].Directives; len(d) != 1 || d[0] != "select" {
+		t.Fatalf("expected the directives [select], got %v", d)
+	}
+	errs := s.Validate()
+	expectErrors(t, errs, []string{"Node cache", "unknown directive instantiate"})
+	var verr *ValidationError
+	if !errors.As(errs[0], &verr) || verr.Line != 9 {
+		t.Fatalf("the error should be located at line 9, got %v", errs[0])
+	}
+}
+
+const unknownTypeTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+    app:
+      type: my.nodes.Unknown
+`
+
+func TestValidateErrorLine(t *testing.T) {
+	s := parseString(t, unknownTypeTemplate)
+	errs := s.Validate()
+	expectErrors(t, errs, []string{"app", "my.nodes.Unknown"})
+	var verr *ValidationError
+	if !errors.As(errs[0], &verr) {
+		t.Fatalf("expected a ValidationError, got %T", errs[0])
+	}
+	if verr.Line != 7 {
+		t.Fatalf("expected the error at line 7, got %v", verr.Line)
+	}
+	if !strings.HasPrefix(verr.Error(), "line 7: ") {
+		t.Fatalf("the error should start with its line, got %q", verr)
+	}
+}
+
+func TestToscaRange(t *testing.T) {
+	nt, err := parseString(t, occurrencesTemplate).FlattenNodeType("tosca.nodes.Compute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, _ := nt.getRequirement("local_storage")
+	if def.Occurrences != (ToscaRange{0, UNBOUNDED}) {
+		t.Fatalf("bad occurrences %v", def.Occurrences)
+	}
+	if def.Occurrences.String() != "[0, UNBOUNDED]" {
+		t.Fatalf("bad notation %v", def.Occurrences)
+	}
+}
+
+const capabilityPropertiesTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            num_cpus: 0
+            mem_size: 4 GB
+            disk_size: 10 GHz
+        os:
+          properties:
+            type: linux
+            distribution: { get_input: distribution }
+`
+
+func TestValidateCapabilityProperties(t *testing.T) {
+	s := parseString(t, capabilityPropertiesTemplate)
+	expectErrors(t, s.Validate(),
+		[]string{"server", "capability host", "property disk_size", "scalar-unit.size"},
+		[]string{"server", "capability host", "property num_cpus", "greater_or_equal 1"},
+	)
+}
+
+const requirementFormsTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+      capabilities:
+        host:
+          properties:
+            num_cpus: 2
+    db:
+      type: tosca.nodes.DBMS
+      requirements:
+        - host:
+            node: tosca.nodes.Compute
+            node_filter:
+              capabilities:
+                - host:
+                    properties:
+                      - num_cpus: { greater_or_equal: 1 }
+    app:
+      type: tosca.nodes.SoftwareComponent
+      requirements:
+        - host:
+            node: server
+            node_filter:
+              capabilities:
+                - host:
+                    properties:
+                      - num_cpus: { greater_or_equal: 1 }
+`
+
+func TestValidateRequirementForms(t *testing.T) {
+	s := parseString(t, requirementFormsTemplate)
+	errs := s.Validate()
+	expectErrors(t, errs, []string{"Node app", "requirement host", "server", "node filter"})
+	var verr *ValidationError
+	if !errors.As(errs[0], &verr) || verr.Line != 23 {
+		t.Fatalf("the error should be located at line 23, got %v", errs[0])
+	}
+}
+
+const substitutionTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  inputs:
+    db_port:
+      type: integer
+  substitution_mappings:
+    node_type: tosca.nodes.Database
+    capabilities:
+      database_endpoint: [ db, database_endpoint ]
+    properties:
+      name: [ db, name ]
+      port: [ db_port ]
+    attributes:
+      tosca_id: [ db, tosca_id ]
+      address: [ db, public_address ]
+  node_templates:
+    dbms:
+      type: tosca.nodes.DBMS
+    db:
+      type: tosca.nodes.Database
+      properties:
+        name: inventory
+      requirements:
+        - host: dbms
+`
+
+func TestSubstitutionMappings(t *testing.T) {
+	s := parseString(t, substitutionTemplate)
+	sm := s.TopologyTemplate.SubstitutionMappings
+	if sm.NodeType != "tosca.nodes.Database" || len(sm.Properties["name"]) != 2 || sm.Properties["name"][1] != "name" {
+		t.Fatalf("bad substitution mappings %+v", sm)
+	}
+	expectErrors(t, s.Validate(),
+		[]string{"Node dbms", "requirement host", "got 0"},
+		[]string{"attributes address", "public_address is not defined by node db"},
+	)
+}
+
+func TestValidateRequiredCapabilities(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Broker:
+    derived_from: tosca.nodes.Root
+    capabilities:
+      queue:
+        type: tosca.capabilities.Endpoint
+        occurrences: [1, UNBOUNDED]
+      admin:
+        type: tosca.capabilities.Endpoint
+        occurrences: [0, 1]
+topology_template:
+  node_templates:
+    broker:
+      type: my.nodes.Broker
+      capabilities:
+        queue:
+          properties:
+            port: 5672
+    missing:
+      type: my.nodes.Broker
+    suppressed:
+      type: my.nodes.Broker
+      capabilities:
+        queue: null
+`)
+	expectErrors(t, s.Validate(),
+		[]string{"line 20", "Node missing: the capability queue required by my.nodes.Broker is missing"},
+		[]string{"line 25", "Node suppressed: the capability queue required by my.nodes.Broker is suppressed"},
+	)
+}
+
+func TestValidateEntrySchemas(t *testing.T) {
+	s := parseString(t, `tosca_definitions_version: tosca_simple_yaml_1_0
+node_types:
+  my.nodes.Proxy:
+    derived_from: tosca.nodes.Root
+    properties:
+      backends:
+        type: list
+      headers:
+        type: map
+        entry_schema:
+          type: string
+topology_template:
+  inputs:
+    tags:
+      type: map
+  node_templates:
+`)
+	expectErrors(t, s.Validate(),
+		[]string{"line 6", "Node type my.nodes.Proxy: property backends: the list has no entry_schema"},
+		[]string{"line 14", "Input tags: the map has no entry_schema"},
+	)
+}
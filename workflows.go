@@ -0,0 +1,128 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Workflow is an imperative workflow of the topology template: a graph of steps
+// related by their on_success and on_failure transitions
+type Workflow struct {
+	Description string                        `yaml:"description,omitempty" json:"description,omitempty"` // The optional description of the workflow.
+	Metadata    map[string]string             `yaml:"metadata,omitempty" json:"metadata,omitempty"`       // Defines a section used to declare additional metadata information.
+	Inputs      map[string]PropertyDefinition `yaml:"inputs,omitempty" json:"inputs,omitempty"`           // The optional definitions of the inputs of the workflow.
+	Steps       map[string]WorkflowStep       `yaml:"steps,omitempty" json:"steps,omitempty"`             // The steps of the workflow, by name.
+}
+
+// WorkflowStep is a step of a workflow: the activities run on a target and the steps that follow
+type WorkflowStep struct {
+	Target             string     `yaml:"target" json:"target"`                                               // The required name of the node template or group the step applies to.
+	TargetRelationship string     `yaml:"target_relationship,omitempty" json:"target_relationship,omitempty"` // The optional name of a requirement of the target, to apply the step to its relationship.
+	OperationHost      string     `yaml:"operation_host,omitempty" json:"operation_host,omitempty"`           // The optional node where the operations of a relationship run: SOURCE or TARGET.
+	Activities         []Activity `yaml:"activities" json:"activities"`                                       // The required list of activities run by the step.
+	OnSuccess          []string   `yaml:"on_success,omitempty" json:"on_success,omitempty"`                   // The optional steps run when the step succeeds.
+	OnFailure          []string   `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`                   // The optional steps run when the step fails.
+}
+
+// stepNames is a list of step names, that may be given as a single name
+type stepNames []string
+
+func (n *stepNames) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*n = stepNames{s}
+		return nil
+	}
+	var names []string
+	if err := unmarshal(&names); err != nil {
+		return err
+	}
+	*n = names
+	return nil
+}
+
+func (w *WorkflowStep) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var str struct {
+		Target             string     `yaml:"target"`
+		TargetRelationship string     `yaml:"target_relationship,omitempty"`
+		OperationHost      string     `yaml:"operation_host,omitempty"`
+		Activities         []Activity `yaml:"activities"`
+		OnSuccess          stepNames  `yaml:"on_success,omitempty"`
+		OnFailure          stepNames  `yaml:"on_failure,omitempty"`
+	}
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	w.Target = str.Target
+	w.TargetRelationship = str.TargetRelationship
+	w.OperationHost = str.OperationHost
+	w.Activities = str.Activities
+	w.OnSuccess = str.OnSuccess
+	w.OnFailure = str.OnFailure
+	return nil
+}
+
+// StepGraph returns the step graph of the workflow: the sorted names of the steps following each step,
+// on success or on failure.
+// It fails if a transition references an unknown step or if the transitions are cyclic.
+func (w Workflow) StepGraph() (map[string][]string, error) {
+	graph := make(map[string][]string, len(w.Steps))
+	for name, step := range w.Steps {
+		next := append(append([]string{}, step.OnSuccess...), step.OnFailure...)
+		sort.Strings(next)
+		for _, n := range next {
+			if _, ok := w.Steps[n]; !ok {
+				return nil, fmt.Errorf("Step %v: unknown step %v", name, n)
+			}
+		}
+		graph[name] = next
+	}
+	names := make([]string, 0, len(graph))
+	for name := range graph {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	done := make(map[string]bool)
+	for _, name := range names {
+		if err := visitStep(graph, name, done, nil); err != nil {
+			return nil, err
+		}
+	}
+	return graph, nil
+}
+
+// visitStep checks that no cycle goes through the steps following step; path holds the steps being visited
+func visitStep(graph map[string][]string, step string, done map[string]bool, path []string) error {
+	for i, n := range path {
+		if n == step {
+			return fmt.Errorf("Cyclic workflow: %v", strings.Join(append(path[i:], step), " -> "))
+		}
+	}
+	if done[step] {
+		return nil
+	}
+	path = append(path, step)
+	for _, next := range graph[step] {
+		if err := visitStep(graph, next, done, path); err != nil {
+			return err
+		}
+	}
+	done[step] = true
+	return nil
+}
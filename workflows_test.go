@@ -0,0 +1,77 @@
+/*
+Copyright 2015 - Olivier Wulveryck
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package toscalib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const workflowTemplate = `tosca_definitions_version: tosca_simple_yaml_1_0
+topology_template:
+  node_templates:
+    server:
+      type: tosca.nodes.Compute
+  workflows:
+    restart:
+      description: Restarts the server
+      steps:
+        stop:
+          target: server
+          activities:
+            - call_operation: Standard.stop
+          on_success: start
+          on_failure: [ alert ]
+        start:
+          target: server
+          activities:
+            - call_operation: Standard.start
+            - set_state: started
+        alert:
+          target: server
+          activities:
+            - set_state: error
+`
+
+func TestWorkflowStepGraph(t *testing.T) {
+	s := parseString(t, workflowTemplate)
+	w := s.TopologyTemplate.Workflows["restart"]
+	if acts := w.Steps["start"].Activities; len(acts) != 2 || acts[0].CallOperation != "Standard.start" || acts[1].SetState != "started" {
+		t.Fatalf("bad activities %+v", acts)
+	}
+	graph, err := w.StepGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"stop": {"alert", "start"}, "start": {}, "alert": {}}
+	if !reflect.DeepEqual(graph, expected) {
+		t.Fatalf("expected the graph %v, got %v", expected, graph)
+	}
+	if errs := s.Validate(); len(errs) != 0 {
+		t.Fatalf("the template should be valid, got %v", errs)
+	}
+}
+
+func TestWorkflowMissingStep(t *testing.T) {
+	s := parseString(t, strings.Replace(workflowTemplate, "on_success: start", "on_success: restart", 1))
+	expectErrors(t, s.Validate(), []string{"Workflow restart", "Step stop: unknown step restart"})
+}
+
+func TestWorkflowCycle(t *testing.T) {
+	s := parseString(t, strings.Replace(workflowTemplate, "- set_state: started", "- set_state: started\n          on_success: stop", 1))
+	expectErrors(t, s.Validate(), []string{"Workflow restart", "Cyclic workflow: start -> stop -> start"})
+}